@@ -3,12 +3,17 @@ package ast
 import (
 	"BubblePL/token"
 	"bytes"
+	"math/big"
 	"strings"
 )
 
 type Node interface {
 	ToLiteral() string
 	String() string
+	// Pos 节点第一个字符的位置
+	Pos() token.Position
+	// End 节点最后一个字符之后的位置
+	End() token.Position
 }
 
 type Statement interface {
@@ -23,6 +28,9 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	// Comments 注释和它们所属的节点：语句之前和语句中间的注释属于语句，块末尾的注释属于块，
+	// 文件末尾的注释属于Program。只有Lexer保留注释时才会记录
+	Comments map[Node][]token.Comment
 }
 
 func (p *Program) ToLiteral() string {
@@ -40,10 +48,25 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Name    *Identifier
+	Pattern Pattern // 解构 let [a, b] = arr 时不为nil，此时Name为nil
+	Value   Expression
 }
 
 func (ls *LetStatement) ToLiteral() string {
@@ -52,7 +75,11 @@ func (ls *LetStatement) ToLiteral() string {
 
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
-	out.WriteString(ls.ToLiteral() + " " + ls.Name.String() + " = ")
+	if ls.Pattern != nil {
+		out.WriteString(ls.ToLiteral() + " " + ls.Pattern.String() + " = ")
+	} else {
+		out.WriteString(ls.ToLiteral() + " " + ls.Name.String() + " = ")
+	}
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
 	}
@@ -63,6 +90,14 @@ func (ls *LetStatement) String() string {
 func (ls *LetStatement) statementNode() {
 }
 
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos
+}
+
+func (ls *LetStatement) End() token.Position {
+	return endOf(ls.Value, ls.Token)
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -80,9 +115,18 @@ func (i *Identifier) expressionNode() {
 
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *Identifier) End() token.Position {
+	return i.Token.End
+}
+
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int // 超出int64范围的字面量，能放进int64时为nil
 }
 
 func (il *IntegerLiteral) expressionNode() {
@@ -96,6 +140,38 @@ func (il *IntegerLiteral) ToLiteral() string {
 	return il.Token.Literal
 }
 
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
+func (il *IntegerLiteral) End() token.Position {
+	return il.Token.End
+}
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) ToLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
+func (fl *FloatLiteral) End() token.Position {
+	return fl.Token.End
+}
+
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
@@ -120,6 +196,189 @@ func (rs *ReturnStatement) ToLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
+func (rs *ReturnStatement) End() token.Position {
+	return endOf(rs.ReturnValue, rs.Token)
+}
+
+// WhileStatement 条件为真时重复执行循环体 while (cond) { }
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {
+}
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while")
+	if ws.Condition != nil {
+		out.WriteString(ws.Condition.String())
+	}
+	out.WriteString(" ")
+	if ws.Body != nil {
+		out.WriteString(ws.Body.String())
+	}
+	return out.String()
+}
+
+func (ws *WhileStatement) ToLiteral() string {
+	return ws.Token.Literal
+}
+
+func (ws *WhileStatement) Pos() token.Position {
+	return ws.Token.Pos
+}
+
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+	return endOf(ws.Condition, ws.Token)
+}
+
+// ForStatement C风格的循环 for (init; cond; update) { }，三个部分都可以省略
+type ForStatement struct {
+	Token     token.Token
+	Init      Statement  // 只在循环中可见
+	Condition Expression // 省略时一直循环
+	Update    Expression
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {
+}
+
+func (fs *ForStatement) String() string {
+	parts := make([]string, 3)
+	if fs.Init != nil {
+		parts[0] = strings.TrimSuffix(fs.Init.String(), ";")
+	}
+	if fs.Condition != nil {
+		parts[1] = fs.Condition.String()
+	}
+	if fs.Update != nil {
+		parts[2] = fs.Update.String()
+	}
+	body := ""
+	if fs.Body != nil {
+		body = fs.Body.String()
+	}
+	return "for (" + strings.Join(parts, "; ") + ") " + body
+}
+
+func (fs *ForStatement) ToLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+func (fs *ForStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return fs.Token.End
+}
+
+// ForInStatement 遍历集合 for (x in xs) { } 或者 for (k, v in xs) { }
+type ForInStatement struct {
+	Token    token.Token
+	Key      *Identifier // 只有一个循环变量时为nil
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode() {
+}
+
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String() + ", ")
+	}
+	out.WriteString(fs.Value.String() + " in ")
+	if fs.Iterable != nil {
+		out.WriteString(fs.Iterable.String())
+	}
+	out.WriteString(") ")
+	if fs.Body != nil {
+		out.WriteString(fs.Body.String())
+	}
+	return out.String()
+}
+
+func (fs *ForInStatement) ToLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForInStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+func (fs *ForInStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return endOf(fs.Iterable, fs.Token)
+}
+
+// BreakStatement 跳出最内层的循环
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode() {
+}
+
+func (bs *BreakStatement) String() string {
+	return bs.Token.Literal + ";"
+}
+
+func (bs *BreakStatement) ToLiteral() string {
+	return bs.Token.Literal
+}
+
+func (bs *BreakStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+func (bs *BreakStatement) End() token.Position {
+	return bs.Token.End
+}
+
+// ContinueStatement 结束本次循环，进入最内层循环的下一次
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode() {
+}
+
+func (cs *ContinueStatement) String() string {
+	return cs.Token.Literal + ";"
+}
+
+func (cs *ContinueStatement) ToLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ContinueStatement) Pos() token.Position {
+	return cs.Token.Pos
+}
+
+func (cs *ContinueStatement) End() token.Position {
+	return cs.Token.End
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -139,6 +398,17 @@ func (exp *ExpressionStatement) String() string {
 	return ""
 }
 
+func (exp *ExpressionStatement) Pos() token.Position {
+	if exp.Expression != nil {
+		return exp.Expression.Pos()
+	}
+	return exp.Token.Pos
+}
+
+func (exp *ExpressionStatement) End() token.Position {
+	return endOf(exp.Expression, exp.Token)
+}
+
 type PrefixExpression struct {
 	Token    token.Token
 	Right    Expression
@@ -162,6 +432,14 @@ func (pe *PrefixExpression) String() string {
 	return out.String()
 }
 
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
+func (pe *PrefixExpression) End() token.Position {
+	return endOf(pe.Right, pe.Token)
+}
+
 type InfixExpression struct {
 	Token    token.Token
 	Right    Expression
@@ -188,6 +466,51 @@ func (ie *InfixExpression) expressionNode() {
 
 }
 
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+
+func (ie *InfixExpression) End() token.Position {
+	return endOf(ie.Right, ie.Token)
+}
+
+// AssignExpression 给已经声明的变量赋值 x = 1，或者复合赋值 x += 1
+type AssignExpression struct {
+	Token    token.Token // = += -= *= /=
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (a *AssignExpression) String() string {
+	value := ""
+	if a.Value != nil {
+		value = a.Value.String()
+	}
+	return a.Target.String() + " " + a.Operator + " " + value
+}
+
+func (a *AssignExpression) ToLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *AssignExpression) expressionNode() {
+}
+
+func (a *AssignExpression) Pos() token.Position {
+	if a.Target != nil {
+		return a.Target.Pos()
+	}
+	return a.Token.Pos
+}
+
+func (a *AssignExpression) End() token.Position {
+	return endOf(a.Value, a.Token)
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -204,9 +527,18 @@ func (b *Boolean) ToLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *Boolean) End() token.Position {
+	return b.Token.End
+}
+
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // {
 	Statements []Statement
+	RBrace     token.Token // }
 }
 
 func (b *BlockStatement) statementNode() {
@@ -224,6 +556,20 @@ func (b *BlockStatement) String() string {
 	return out.String()
 }
 
+func (b *BlockStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *BlockStatement) End() token.Position {
+	if b.RBrace.End.IsValid() {
+		return b.RBrace.End
+	}
+	if len(b.Statements) > 0 {
+		return b.Statements[len(b.Statements)-1].End()
+	}
+	return b.Token.End
+}
+
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -251,9 +597,60 @@ func (i *IfExpression) String() string {
 	return out.String()
 }
 
+func (i *IfExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *IfExpression) End() token.Position {
+	if i.Alternative != nil {
+		return i.Alternative.End()
+	}
+	if i.Consequence != nil {
+		return i.Consequence.End()
+	}
+	return endOf(i.Condition, i.Token)
+}
+
+// ConditionalExpression 条件表达式 cond ? a : b，只计算被选中的一边
+type ConditionalExpression struct {
+	Token       token.Token // ?
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (c *ConditionalExpression) expressionNode() {
+}
+
+func (c *ConditionalExpression) ToLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *ConditionalExpression) String() string {
+	consequence, alternative := "", ""
+	if c.Consequence != nil {
+		consequence = c.Consequence.String()
+	}
+	if c.Alternative != nil {
+		alternative = c.Alternative.String()
+	}
+	return "(" + c.Condition.String() + " ? " + consequence + " : " + alternative + ")"
+}
+
+func (c *ConditionalExpression) Pos() token.Position {
+	return c.Condition.Pos()
+}
+
+func (c *ConditionalExpression) End() token.Position {
+	if c.Alternative != nil {
+		return c.Alternative.End()
+	}
+	return endOf(c.Consequence, c.Token)
+}
+
 type FunctionExpression struct {
 	Token      token.Token
-	Parameters []*Identifier
+	Parameters []*Parameter
 	Body       *BlockStatement
 }
 
@@ -280,10 +677,124 @@ func (f *FunctionExpression) ToLiteral() string {
 func (f *FunctionExpression) expressionNode() {
 }
 
+func (f *FunctionExpression) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *FunctionExpression) End() token.Position {
+	if f.Body != nil {
+		return f.Body.End()
+	}
+	return f.Token.End
+}
+
+// Parameter 函数的一个参数，可以带默认值 x = 1，或者是收集剩余参数的 ...rest，
+// 也可以是解构参数 [a, b] 或者 {name}
+type Parameter struct {
+	Token   token.Token // 参数名，剩余参数为 ...，解构参数为 [ 或者 {
+	Name    *Identifier
+	Pattern Pattern    // 解构参数的模式，此时Name为nil
+	Default Expression // 没有默认值时为nil
+	Rest    bool
+}
+
+func (p *Parameter) ToLiteral() string {
+	return p.Token.Literal
+}
+
+func (p *Parameter) String() string {
+	name := ""
+	if p.Pattern != nil {
+		name = p.Pattern.String()
+	} else if p.Name != nil {
+		name = p.Name.String()
+	}
+	switch {
+	case p.Rest:
+		return "..." + name
+	case p.Default != nil:
+		return name + " = " + p.Default.String()
+	default:
+		return name
+	}
+}
+
+func (p *Parameter) Pos() token.Position {
+	return p.Token.Pos
+}
+
+func (p *Parameter) End() token.Position {
+	if p.Default != nil {
+		return p.Default.End()
+	}
+	if p.Pattern != nil {
+		return p.Pattern.End()
+	}
+	return endOf(p.Name, p.Token)
+}
+
+// SpreadExpression 调用函数时把数组展开成多个参数 f(...arr)
+type SpreadExpression struct {
+	Token token.Token // ...
+	Value Expression
+}
+
+func (s *SpreadExpression) ToLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *SpreadExpression) expressionNode() {
+}
+
+func (s *SpreadExpression) String() string {
+	if s.Value == nil {
+		return "..."
+	}
+	return "..." + s.Value.String()
+}
+
+func (s *SpreadExpression) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *SpreadExpression) End() token.Position {
+	return endOf(s.Value, s.Token)
+}
+
+// NamedArgument 调用函数时按参数名传递的参数 f(y: 2)
+type NamedArgument struct {
+	Token token.Token // :
+	Name  *Identifier
+	Value Expression
+}
+
+func (n *NamedArgument) ToLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NamedArgument) expressionNode() {
+}
+
+func (n *NamedArgument) String() string {
+	if n.Value == nil {
+		return n.Name.String() + ":"
+	}
+	return n.Name.String() + ": " + n.Value.String()
+}
+
+func (n *NamedArgument) Pos() token.Position {
+	return n.Name.Pos()
+}
+
+func (n *NamedArgument) End() token.Position {
+	return endOf(n.Value, n.Token)
+}
+
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // (
 	Function  Expression
 	Arguments []Expression
+	RParen    token.Token // )
 }
 
 func (c *CallExpression) ToLiteral() string {
@@ -307,6 +818,55 @@ func (c *CallExpression) String() string {
 	return out.String()
 }
 
+func (c *CallExpression) Pos() token.Position {
+	if c.Function != nil {
+		return c.Function.Pos()
+	}
+	return c.Token.Pos
+}
+
+func (c *CallExpression) End() token.Position {
+	return closingEnd(c.RParen, c.Token)
+}
+
+// MethodCallExpression 方法调用 obj.method(args)。obj是哈希表并且有method字段时调用字段中的函数，
+// 函数中的self是obj；否则调用obj的类型的内置方法，例如 "abc".upper()
+type MethodCallExpression struct {
+	Token     token.Token // . 或者 ?.
+	Object    Expression
+	Method    *Identifier
+	Arguments []Expression
+	RParen    token.Token // )
+	Optional  bool        // obj?.method()，Object为null时整个访问链的结果是null
+}
+
+func (m *MethodCallExpression) ToLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MethodCallExpression) expressionNode() {
+}
+
+func (m *MethodCallExpression) String() string {
+	var args []string
+	for _, a := range m.Arguments {
+		args = append(args, a.String())
+	}
+	dot := "."
+	if m.Optional {
+		dot = "?."
+	}
+	return m.Object.String() + dot + m.Method.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (m *MethodCallExpression) Pos() token.Position {
+	return m.Object.Pos()
+}
+
+func (m *MethodCallExpression) End() token.Position {
+	return closingEnd(m.RParen, m.Token)
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -323,9 +883,60 @@ func (s *StringLiteral) ToLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *StringLiteral) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *StringLiteral) End() token.Position {
+	return s.Token.End
+}
+
+// InterpolatedString 插值字符串 "count: ${n + 1}"，Parts中的文本部分是StringLiteral，其余是嵌入的表达式
+type InterpolatedString struct {
+	Token token.Token // INTERP_START
+	Parts []Expression
+	Close token.Token // INTERP_END
+}
+
+func (is *InterpolatedString) expressionNode() {
+}
+
+func (is *InterpolatedString) ToLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString("\"")
+	for _, part := range is.Parts {
+		if str, ok := part.(*StringLiteral); ok {
+			out.WriteString(str.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
+
+func (is *InterpolatedString) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *InterpolatedString) End() token.Position {
+	if is.Close.End.IsValid() {
+		return is.Close.End
+	}
+	if len(is.Parts) > 0 {
+		return is.Parts[len(is.Parts)-1].End()
+	}
+	return is.Token.End
+}
+
 type ArrayLiteral struct {
-	Token    token.Token
+	Token    token.Token // [
 	Elements []Expression
+	RBracket token.Token // ]
 }
 
 func (al *ArrayLiteral) ToLiteral() string {
@@ -347,10 +958,20 @@ func (al *ArrayLiteral) expressionNode() {
 
 }
 
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
+
+func (al *ArrayLiteral) End() token.Position {
+	return closingEnd(al.RBracket, al.Token)
+}
+
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token // [ 或者 ?[
+	Left     Expression
+	Index    Expression
+	RBracket token.Token // ]
+	Optional bool        // arr?[i]，Left为null时整个访问链的结果是null
 }
 
 func (i *IndexExpression) expressionNode() {
@@ -361,6 +982,9 @@ func (i *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(i.Left.String())
+	if i.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(i.Index.String())
 	out.WriteString("])")
@@ -371,9 +995,62 @@ func (i *IndexExpression) ToLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IndexExpression) Pos() token.Position {
+	if i.Left != nil {
+		return i.Left.Pos()
+	}
+	return i.Token.Pos
+}
+
+func (i *IndexExpression) End() token.Position {
+	return closingEnd(i.RBracket, i.Token)
+}
+
+// DotExpression 用名字访问哈希表中的字段 h.k，相当于 h["k"]
+type DotExpression struct {
+	Token    token.Token // . 或者 ?.
+	Left     Expression
+	Name     *Identifier
+	Optional bool // h?.k，Left为null时整个访问链的结果是null
+}
+
+func (d *DotExpression) expressionNode() {
+}
+
+func (d *DotExpression) String() string {
+	name := ""
+	if d.Name != nil {
+		name = d.Name.String()
+	}
+	dot := "."
+	if d.Optional {
+		dot = "?."
+	}
+	return "(" + d.Left.String() + dot + name + ")"
+}
+
+func (d *DotExpression) ToLiteral() string {
+	return d.Token.Literal
+}
+
+func (d *DotExpression) Pos() token.Position {
+	if d.Left != nil {
+		return d.Left.Pos()
+	}
+	return d.Token.Pos
+}
+
+func (d *DotExpression) End() token.Position {
+	if d.Name != nil {
+		return d.Name.End()
+	}
+	return d.Token.End
+}
+
 type HashLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token  token.Token // {
+	Pairs  map[Expression]Expression
+	RBrace token.Token // }
 }
 
 func (hl *HashLiteral) expressionNode() {
@@ -384,6 +1061,14 @@ func (hl *HashLiteral) ToLiteral() string {
 	return hl.Token.Literal
 }
 
+func (hl *HashLiteral) Pos() token.Position {
+	return hl.Token.Pos
+}
+
+func (hl *HashLiteral) End() token.Position {
+	return closingEnd(hl.RBrace, hl.Token)
+}
+
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	var pairs []string
@@ -395,3 +1080,19 @@ func (hl *HashLiteral) String() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+// endOf 返回节点的结束位置，节点为空（解析出错）时使用tk的结束位置
+func endOf(n Node, tk token.Token) token.Position {
+	if n != nil {
+		return n.End()
+	}
+	return tk.End
+}
+
+// closingEnd 返回闭合符号的结束位置，闭合符号缺失（解析出错）时使用open的结束位置
+func closingEnd(closing, open token.Token) token.Position {
+	if closing.End.IsValid() {
+		return closing.End
+	}
+	return open.End
+}
@@ -32,3 +32,44 @@ func TestString(t *testing.T) {
 		t.Errorf("program wrong. got=%q", program.String())
 	}
 }
+
+func TestDump(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&LetStatement{
+			Token: token.Token{Type: token.LET, Literal: "let"},
+			Name: &Identifier{
+				Token: token.Token{Type: token.IDENT, Literal: "x"},
+				Value: "x",
+			},
+			Value: &PrefixExpression{
+				Token:    token.Token{Type: token.MINUS, Literal: "-"},
+				Operator: "-",
+				Right: &IntegerLiteral{
+					Token: token.Token{Type: token.INT, Literal: "5"},
+					Value: 5,
+				},
+			},
+		},
+		&ExpressionStatement{
+			Token: token.Token{Type: token.LBRACKET, Literal: "["},
+			Expression: &ArrayLiteral{
+				Token:    token.Token{Type: token.LBRACKET, Literal: "["},
+				Elements: []Expression{&Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}},
+			},
+		},
+	}}
+
+	expected := `Program [---]
+  Statements[0]: LetStatement [---]
+    Name: Identifier [---] Value="x"
+    Pattern: nil
+    Value: PrefixExpression [---] Operator="-"
+      Right: IntegerLiteral [---] Value=5
+  Statements[1]: ExpressionStatement [---]
+    Expression: ArrayLiteral [---]
+      Elements[0]: Boolean [---] Value=true
+`
+	if got := Dump(program); got != expected {
+		t.Errorf("Dump wrong. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
@@ -0,0 +1,86 @@
+package ast
+
+import (
+	"BubblePL/token"
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(token.Token{})
+)
+
+// Dump 以缩进的树形结构输出节点，每个节点占一行，包含节点类型、位置范围和节点自身的值，
+// 子节点按照字段缩进输出
+func Dump(node Node) string {
+	var out bytes.Buffer
+	dumpNode(&out, "", node, 0)
+	return out.String()
+}
+
+func dumpNode(out *bytes.Buffer, label string, node Node, indent int) {
+	out.WriteString(strings.Repeat("  ", indent))
+	out.WriteString(label)
+	value := reflect.ValueOf(node)
+	if node == nil || value.Kind() == reflect.Ptr && value.IsNil() {
+		out.WriteString("nil\n")
+		return
+	}
+	elem := reflect.Indirect(value)
+	fmt.Fprintf(out, "%s [%s-%s]", elem.Type().Name(), node.Pos(), node.End())
+	if elem.Kind() != reflect.Struct {
+		out.WriteString("\n")
+		return
+	}
+
+	// 先在同一行输出节点自身的值，再逐个输出子节点
+	type child struct {
+		label string
+		node  Node
+	}
+	var children []child
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Type().Field(i)
+		fieldValue := elem.Field(i)
+		if !field.IsExported() || field.Type == tokenType || field.Name == "Comments" {
+			continue
+		}
+		switch {
+		case field.Type.Implements(nodeType):
+			n, _ := fieldValue.Interface().(Node)
+			children = append(children, child{field.Name + ": ", n})
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Implements(nodeType):
+			for j := 0; j < fieldValue.Len(); j++ {
+				n, _ := fieldValue.Index(j).Interface().(Node)
+				children = append(children, child{fmt.Sprintf("%s[%d]: ", field.Name, j), n})
+			}
+		case field.Type.Kind() == reflect.Map && field.Type.Key().Implements(nodeType) && field.Type.Elem().Implements(nodeType):
+			keys := fieldValue.MapKeys()
+			// map没有顺序，按照源代码中的位置输出
+			sort.Slice(keys, func(a, b int) bool {
+				return keys[a].Interface().(Node).Pos().Offset < keys[b].Interface().(Node).Pos().Offset
+			})
+			for _, key := range keys {
+				k, _ := key.Interface().(Node)
+				v, _ := fieldValue.MapIndex(key).Interface().(Node)
+				children = append(children, child{field.Name + ".Key: ", k}, child{field.Name + ".Value: ", v})
+			}
+		case fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil():
+			// 没有设置的可选字段不输出
+		default:
+			if fieldValue.Kind() == reflect.String {
+				fmt.Fprintf(out, " %s=%q", field.Name, fieldValue.String())
+			} else {
+				fmt.Fprintf(out, " %s=%v", field.Name, fieldValue.Interface())
+			}
+		}
+	}
+	out.WriteString("\n")
+	for _, c := range children {
+		dumpNode(out, c.label, c.node, indent+1)
+	}
+}
@@ -0,0 +1,261 @@
+package ast
+
+import (
+	"BubblePL/token"
+	"strings"
+)
+
+// Pattern 模式，用在match的分支、解构的let和函数参数中，检查值的形状并绑定变量
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// WildcardPattern _ 匹配任何值，不绑定变量
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (w *WildcardPattern) patternNode() {
+}
+
+func (w *WildcardPattern) ToLiteral() string {
+	return w.Token.Literal
+}
+
+func (w *WildcardPattern) String() string {
+	return "_"
+}
+
+func (w *WildcardPattern) Pos() token.Position {
+	return w.Token.Pos
+}
+
+func (w *WildcardPattern) End() token.Position {
+	return w.Token.End
+}
+
+// BindingPattern 匹配任何值，并把值绑定到Name。
+// 在数组和哈希表模式中可以带默认值 x = 1，对应的元素或者键不存在时使用
+type BindingPattern struct {
+	Name    *Identifier
+	Default Expression // 没有默认值时为nil
+}
+
+func (b *BindingPattern) patternNode() {
+}
+
+func (b *BindingPattern) ToLiteral() string {
+	return b.Name.ToLiteral()
+}
+
+func (b *BindingPattern) String() string {
+	if b.Default != nil {
+		return b.Name.String() + " = " + b.Default.String()
+	}
+	return b.Name.String()
+}
+
+func (b *BindingPattern) Pos() token.Position {
+	return b.Name.Pos()
+}
+
+func (b *BindingPattern) End() token.Position {
+	if b.Default != nil {
+		return b.Default.End()
+	}
+	return b.Name.End()
+}
+
+// LiteralPattern 和字面量相等时匹配，例如 1、-1.5、"s"、true
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (l *LiteralPattern) patternNode() {
+}
+
+func (l *LiteralPattern) ToLiteral() string {
+	return l.Value.ToLiteral()
+}
+
+func (l *LiteralPattern) String() string {
+	if s, ok := l.Value.(*StringLiteral); ok {
+		return `"` + s.Value + `"`
+	}
+	return l.Value.String()
+}
+
+func (l *LiteralPattern) Pos() token.Position {
+	return l.Value.Pos()
+}
+
+func (l *LiteralPattern) End() token.Position {
+	return l.Value.End()
+}
+
+// ArrayPattern [a, b, ...rest]，没有Rest时数组的长度必须和Elements相同
+type ArrayPattern struct {
+	Token    token.Token // [
+	Elements []Pattern
+	Rest     *Identifier // 没有 ...rest 时为nil
+	RBracket token.Token // ]
+}
+
+func (a *ArrayPattern) patternNode() {
+}
+
+func (a *ArrayPattern) ToLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *ArrayPattern) String() string {
+	var elements []string
+	for _, e := range a.Elements {
+		elements = append(elements, e.String())
+	}
+	if a.Rest != nil {
+		elements = append(elements, "..."+a.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (a *ArrayPattern) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a *ArrayPattern) End() token.Position {
+	return closingEnd(a.RBracket, a.Token)
+}
+
+// HashPattern {"k": v}，哈希表中有所有的键并且对应的值匹配时匹配，不要求没有其它的键。
+// 简写 {name} 的键是Token为标识符的StringLiteral，值是同名的BindingPattern
+type HashPattern struct {
+	Token  token.Token // {
+	Keys   []Expression
+	Values []Pattern   // Values[i]是Keys[i]对应的值的模式
+	RBrace token.Token // }
+}
+
+func (h *HashPattern) patternNode() {
+}
+
+func (h *HashPattern) ToLiteral() string {
+	return h.Token.Literal
+}
+
+func (h *HashPattern) String() string {
+	var pairs []string
+	for i, key := range h.Keys {
+		k := key.String()
+		if s, ok := key.(*StringLiteral); ok {
+			if s.Token.Type == token.IDENT {
+				pairs = append(pairs, h.Values[i].String())
+				continue
+			}
+			k = `"` + s.Value + `"`
+		}
+		pairs = append(pairs, k+": "+h.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (h *HashPattern) Pos() token.Position {
+	return h.Token.Pos
+}
+
+func (h *HashPattern) End() token.Position {
+	return closingEnd(h.RBrace, h.Token)
+}
+
+// PatternNames 模式中按顺序出现的所有变量
+func PatternNames(pattern Pattern) []*Identifier {
+	var names []*Identifier
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		names = append(names, pattern.Name)
+	case *ArrayPattern:
+		for _, element := range pattern.Elements {
+			names = append(names, PatternNames(element)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+		}
+	case *HashPattern:
+		for _, value := range pattern.Values {
+			names = append(names, PatternNames(value)...)
+		}
+	}
+	return names
+}
+
+// MatchExpression match (value) { pattern => result, ... }，按顺序使用第一个匹配的分支
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+	RBrace  token.Token // }
+}
+
+func (m *MatchExpression) expressionNode() {
+}
+
+func (m *MatchExpression) ToLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MatchExpression) String() string {
+	var arms []string
+	for _, arm := range m.Arms {
+		arms = append(arms, arm.String())
+	}
+	subject := ""
+	if m.Subject != nil {
+		subject = m.Subject.String()
+	}
+	return "match (" + subject + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+func (m *MatchExpression) Pos() token.Position {
+	return m.Token.Pos
+}
+
+func (m *MatchExpression) End() token.Position {
+	return closingEnd(m.RBrace, m.Token)
+}
+
+// MatchArm match中的一个分支 pattern if guard => body，Guard为nil时只检查模式
+type MatchArm struct {
+	Token   token.Token // =>
+	Pattern Pattern
+	Guard   Expression
+	Body    *BlockStatement // 分支是表达式时是只有这个表达式的块
+}
+
+func (m *MatchArm) ToLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MatchArm) String() string {
+	var out strings.Builder
+	out.WriteString(m.Pattern.String())
+	if m.Guard != nil {
+		out.WriteString(" if " + m.Guard.String())
+	}
+	out.WriteString(" => ")
+	if m.Body != nil {
+		out.WriteString(m.Body.String())
+	}
+	return out.String()
+}
+
+func (m *MatchArm) Pos() token.Position {
+	return m.Pattern.Pos()
+}
+
+func (m *MatchArm) End() token.Position {
+	if m.Body != nil {
+		return m.Body.End()
+	}
+	return m.Token.End
+}
@@ -3,29 +3,28 @@ package evaluator
 import (
 	"BubblePL/object"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin{
-	"len": {func(args ...object.Object) object.Object {
-		if len(args) != 1 {
-			return newError("wrong number of arguments. got=%d, want=1", len(args))
+	"len": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("len", args, argTypes{object.STRING_OBJ, object.ARRAY_OBJ}); err != nil {
+			return err
 		}
 		switch arg := args[0].(type) {
 		case *object.String:
-			return &object.Integer{Value: int64(len(arg.Value))}
-		case *object.Array:
-			return &object.Integer{Value: int64(len(arg.Elements))}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 		default:
-			return newError("argument to `len` not supported, got=%s", args[0].Type())
+			return &object.Integer{Value: int64(len(arg.(*object.Array).Elements))}
 		}
-
 	}},
 	"first": {Fn: func(args ...object.Object) object.Object {
-		if len(args) != 1 {
-			return newError("wrong number of arguments. got=%d, want=1", len(args))
-		}
-		if args[0].Type() != object.ARRAY_OBJ {
-			return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+		if err := checkArgs("first", args, argTypes{object.ARRAY_OBJ}); err != nil {
+			return err
 		}
 		arr := args[0].(*object.Array)
 		if len(arr.Elements) > 0 {
@@ -34,11 +33,8 @@ var builtins = map[string]*object.Builtin{
 		return NULL
 	}},
 	"last": {Fn: func(args ...object.Object) object.Object {
-		if len(args) != 1 {
-			return newError("wrong number of arguments. got=%d, want=1", len(args))
-		}
-		if args[0].Type() != object.ARRAY_OBJ {
-			return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+		if err := checkArgs("last", args, argTypes{object.ARRAY_OBJ}); err != nil {
+			return err
 		}
 		arr := args[0].(*object.Array)
 		if len(arr.Elements) > 0 {
@@ -47,11 +43,8 @@ var builtins = map[string]*object.Builtin{
 		return NULL
 	}},
 	"rest": {Fn: func(args ...object.Object) object.Object {
-		if len(args) != 1 {
-			return newError("wrong number of arguments. got=%d, want=1", len(args))
-		}
-		if args[0].Type() != object.ARRAY_OBJ {
-			return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+		if err := checkArgs("rest", args, argTypes{object.ARRAY_OBJ}); err != nil {
+			return err
 		}
 		arr := args[0].(*object.Array)
 
@@ -63,11 +56,8 @@ var builtins = map[string]*object.Builtin{
 		return NULL
 	}},
 	"push": {Fn: func(args ...object.Object) object.Object {
-		if len(args) != 2 {
-			return newError("wrong number of arguments. got=%d, want=1", len(args))
-		}
-		if args[0].Type() != object.ARRAY_OBJ {
-			return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+		if err := checkArgs("push", args, argTypes{object.ARRAY_OBJ}, nil); err != nil {
+			return err
 		}
 		arr := args[0].(*object.Array)
 		length := len(arr.Elements)
@@ -77,11 +67,8 @@ var builtins = map[string]*object.Builtin{
 		return &object.Array{Elements: newElements}
 	}},
 	"pop": {Fn: func(args ...object.Object) object.Object {
-		if len(args) != 1 {
-			return newError("wrong number of arguments. got=%d, want=1", len(args))
-		}
-		if args[0].Type() != object.ARRAY_OBJ {
-			return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+		if err := checkArgs("pop", args, argTypes{object.ARRAY_OBJ}); err != nil {
+			return err
 		}
 		arr := args[0].(*object.Array)
 		length := len(arr.Elements)
@@ -92,6 +79,124 @@ var builtins = map[string]*object.Builtin{
 		}
 		return NULL
 	}},
+	"str": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("str", args, nil); err != nil {
+			return err
+		}
+		if str, ok := args[0].(*object.String); ok {
+			return str
+		}
+		return &object.String{Value: stringify(args[0])}
+	}},
+	"bytes": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("bytes", args, argTypes{object.STRING_OBJ}); err != nil {
+			return err
+		}
+		str := args[0].(*object.String)
+		elements := make([]object.Object, len(str.Value))
+		for i := 0; i < len(str.Value); i++ {
+			elements[i] = &object.Integer{Value: int64(str.Value[i])}
+		}
+		return &object.Array{Elements: elements}
+	}},
+	"chars": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("chars", args, argTypes{object.STRING_OBJ}); err != nil {
+			return err
+		}
+		str := args[0].(*object.String)
+		elements := make([]object.Object, 0, len(str.Value))
+		for _, r := range str.Value {
+			elements = append(elements, &object.String{Value: string(r)})
+		}
+		return &object.Array{Elements: elements}
+	}},
+	"ord": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("ord", args, argTypes{object.STRING_OBJ}); err != nil {
+			return err
+		}
+		str := args[0].(*object.String)
+		r, size := utf8.DecodeRuneInString(str.Value)
+		if size == 0 || size != len(str.Value) {
+			return newError("argument to `ord` must be a single character, got %q", str.Value)
+		}
+		return &object.Integer{Value: int64(r)}
+	}},
+	"chr": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("chr", args, argTypes{object.INTEGER_OBJ}); err != nil {
+			return err
+		}
+		code, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("invalid code point for `chr`: %s", args[0].Inspect())
+		}
+		if code.Value < 0 || code.Value > utf8.MaxRune || !utf8.ValidRune(rune(code.Value)) {
+			return newError("invalid code point for `chr`: %d", code.Value)
+		}
+		return &object.String{Value: string(rune(code.Value))}
+	}},
+	"int": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("int", args, argTypes{object.INTEGER_OBJ, object.FLOAT_OBJ, object.STRING_OBJ}); err != nil {
+			return err
+		}
+		switch arg := args[0].(type) {
+		case *object.Float:
+			if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+				return newError("cannot convert %s to INTEGER", arg.Inspect())
+			}
+			value, _ := big.NewFloat(arg.Value).Int(nil)
+			return newInteger(value)
+		case *object.String:
+			value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+			if !ok {
+				return newError("cannot convert %q to INTEGER", arg.Value)
+			}
+			return newInteger(value)
+		default:
+			return arg
+		}
+	}},
+	"float": {Fn: func(args ...object.Object) object.Object {
+		if err := checkArgs("float", args, argTypes{object.INTEGER_OBJ, object.FLOAT_OBJ, object.STRING_OBJ}); err != nil {
+			return err
+		}
+		switch arg := args[0].(type) {
+		case *object.String:
+			value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+			if err != nil {
+				return newError("cannot convert %q to FLOAT", arg.Value)
+			}
+			return &object.Float{Value: value}
+		default:
+			return &object.Float{Value: toFloat(arg)}
+		}
+	}},
+	"range": {Fn: func(args ...object.Object) object.Object {
+		if len(args) < 1 || len(args) > 3 {
+			return newError("range expects 1 to 3 arguments, got %d", len(args))
+		}
+		bounds := make([]int64, len(args))
+		for i, arg := range args {
+			n, ok := arg.(*object.Integer)
+			switch {
+			case !ok && arg.Type() == object.INTEGER_OBJ:
+				return newError("argument %d to `range` does not fit in 64 bits: %s", i+1, arg.Inspect())
+			case !ok:
+				return newError("argument %d to `range` must be INTEGER, got %s", i+1, arg.Type())
+			}
+			bounds[i] = n.Value
+		}
+		r := &object.Range{Start: 0, Stop: bounds[0], Step: 1}
+		if len(bounds) > 1 {
+			r.Start, r.Stop = bounds[0], bounds[1]
+		}
+		if len(bounds) > 2 {
+			r.Step = bounds[2]
+		}
+		if r.Step == 0 {
+			return newError("step of `range` must not be zero")
+		}
+		return r
+	}},
 	"print": {Fn: func(args ...object.Object) object.Object {
 		for _, arg := range args {
 			fmt.Println(arg.Inspect())
@@ -99,3 +204,49 @@ var builtins = map[string]*object.Builtin{
 		return NULL
 	}},
 }
+
+// argTypes 内置函数的一个参数允许的类型，为nil时允许任意类型
+type argTypes []object.ObjectType
+
+// checkArgs 检查内置函数name的参数，params的长度就是参数个数，params[i]是第i个参数允许的类型
+func checkArgs(name string, args []object.Object, params ...argTypes) *object.Error {
+	if len(args) != len(params) {
+		return arityError(name, len(params), len(args))
+	}
+	for i, allowed := range params {
+		if allowed == nil || containsType(allowed, args[i].Type()) {
+			continue
+		}
+		names := make([]string, len(allowed))
+		for j, t := range allowed {
+			names[j] = string(t)
+		}
+		argument := "argument"
+		if len(params) > 1 {
+			argument = fmt.Sprintf("argument %d", i+1)
+		}
+		return newError("%s to `%s` must be %s, got %s", argument, name, strings.Join(names, " or "), args[i].Type())
+	}
+	return nil
+}
+
+func containsType(types argTypes, t object.ObjectType) bool {
+	for _, allowed := range types {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
+
+// arityError 参数个数错误，用户定义的函数和内置函数使用同样的信息
+func arityError(name string, want, got int) *object.Error {
+	return newError("%s expects %d %s, got %d", name, want, pluralArguments(want), got)
+}
+
+func pluralArguments(n int) string {
+	if n == 1 {
+		return "argument"
+	}
+	return "arguments"
+}
@@ -3,58 +3,113 @@ package evaluator
 import (
 	"BubblePL/ast"
 	"BubblePL/object"
+	"BubblePL/token"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+	// 错误第一次返回时记录产生错误的节点的位置
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isInterrupted(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(node.Operator, right, env.CheckedArithmetic())
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+		if node.Operator == "??" {
+			return evalNullishExpression(node, env)
+		}
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isInterrupted(left) {
 			return left
 		}
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isInterrupted(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env.CheckedArithmetic())
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isInterrupted(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return evalDestructuringLet(node, env)
+		}
+		if env.IsConst(node.Name.Value) {
+			return newError("cannot redeclare constant %s", node.Name.Value)
+		}
 		value := Eval(node.Value, env)
-		if isError(value) {
+		if isInterrupted(value) {
 			return value
 		}
-		env.Set(node.Name.Value, value)
+		if fn, ok := value.(*object.Function); ok && fn.Name == "" {
+			fn.Name = node.Name.Value
+		}
+		if node.Token.Type == token.CONST {
+			env.SetConst(node.Name.Value, value)
+		} else {
+			env.Set(node.Name.Value, value)
+		}
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionExpression:
@@ -65,33 +120,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isInterrupted(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, named, err := evalArguments(node.Arguments, env)
+		if err != nil {
+			return err
 		}
-		return applyFunction(function, args)
+		return applyFunction(function, args, named, node.Pos())
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isInterrupted(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
-	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+	case *ast.DotExpression, *ast.IndexExpression, *ast.MethodCallExpression:
+		result, _ := evalChain(node.(ast.Expression), env)
+		return result
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 	}
@@ -102,7 +152,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 	pairs := make(map[object.HashKey]object.HashPair)
 	for keyNode, valueNode := range node.Pairs {
 		key := Eval(keyNode, env)
-		if isError(key) {
+		if isInterrupted(key) {
 			return key
 		}
 		hashKey, ok := key.(object.Hashable)
@@ -112,7 +162,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		}
 
 		value := Eval(valueNode, env)
-		if isError(value) {
+		if isInterrupted(value) {
 			return value
 		}
 		hashed := hashKey.HashKey()
@@ -128,6 +178,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -151,7 +203,12 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
+	// 大整数一定超出了范围
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return NULL
+	}
+	idx := i.Value
 	max := int64(len(arrayObject.Elements) - 1)
 	if idx < 0 || idx > max {
 		return NULL
@@ -159,12 +216,125 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// evalStringIndexExpression 按字符取出字符串中的一个字符
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return NULL
+	}
+	idx := i.Value
+	if idx < 0 || idx >= int64(len(runes)) {
+		return NULL
+	}
+	return &object.String{Value: string(runes[idx])}
+}
+
+// extendFunctionEnv 把调用参数绑定到函数的参数上。没有传递的参数使用默认值，
+// 默认值在调用时计算，可以使用前面的参数；多余的参数收集到 ...rest 中，解构参数按模式绑定
+func extendFunctionEnv(fn *object.Function, args []object.Object, named []namedArgument) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
-	for i, p := range fn.Parameters {
-		env.Set(p.Value, args[i])
+	params := fn.Parameters
+	var rest *ast.Parameter
+	if n := len(params); n > 0 && params[n-1].Rest {
+		rest = params[n-1]
+		params = params[:n-1]
+	}
+	if len(args) > len(params) && rest == nil {
+		return nil, functionArityError(fn, len(args)+len(named))
+	}
+
+	values := make([]object.Object, len(params))
+	passed := make([]bool, len(params)) // 参数是否传递了值，值本身不能用来判断
+	copy(values, args)
+	for i := 0; i < len(args) && i < len(params); i++ {
+		passed[i] = true
+	}
+	for _, arg := range named {
+		i := parameterIndex(params, arg.name)
+		if i < 0 {
+			return nil, newError("%s has no parameter named %s", functionName(fn), arg.name)
+		}
+		if passed[i] {
+			return nil, newError("%s got multiple values for parameter %s", functionName(fn), arg.name)
+		}
+		values[i] = arg.value
+		passed[i] = true
+	}
+	for i, p := range params {
+		value := values[i]
+		if !passed[i] {
+			if p.Default == nil {
+				if len(named) == 0 {
+					return nil, functionArityError(fn, len(args))
+				}
+				return nil, newError("%s missing argument for parameter %s", functionName(fn), p.String())
+			}
+			value = Eval(p.Default, env)
+			if isInterrupted(value) {
+				return nil, value
+			}
+		}
+		if p.Pattern == nil {
+			env.Set(p.Name.Value, value)
+			continue
+		}
+		mismatch, err := matchPattern(p.Pattern, value, env, env.Set)
+		if err != nil {
+			return nil, err
+		}
+		if mismatch != nil {
+			return nil, newError("%s cannot destructure argument for parameter %s: %s", functionName(fn), p.Pattern.String(), mismatch.Message)
+		}
+	}
+	if rest != nil {
+		elements := []object.Object{}
+		if len(args) > len(params) {
+			elements = append(elements, args[len(params):]...)
+		}
+		env.Set(rest.Name.Value, &object.Array{Elements: elements})
+	}
+	return env, nil
+}
+
+func parameterIndex(params []*ast.Parameter, name string) int {
+	for i, p := range params {
+		if p.Name != nil && p.Name.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func functionName(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
+// functionArityError 用户定义的函数参数个数错误，有默认值或者剩余参数时给出参数个数的范围
+func functionArityError(fn *object.Function, got int) *object.Error {
+	min, max := 0, 0
+	for _, p := range fn.Parameters {
+		switch {
+		case p.Rest:
+			max = -1
+		case p.Default == nil:
+			min++
+			max++
+		default:
+			max++
+		}
+	}
+	switch {
+	case max < 0:
+		return newError("%s expects at least %d %s, got %d", functionName(fn), min, pluralArguments(min), got)
+	case min == max:
+		return arityError(functionName(fn), min, got)
+	default:
+		return newError("%s expects %d to %d arguments, got %d", functionName(fn), min, max, got)
 	}
-	return env
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -174,13 +344,28 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(fn object.Object, args []object.Object, named []namedArgument, callSite token.Position) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, bindErr := extendFunctionEnv(fn, args, named)
+		if bindErr != nil {
+			if err, ok := bindErr.(*object.Error); ok && !err.Pos.IsValid() {
+				err.Pos = callSite
+			}
+			return bindErr
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
+		// 错误离开函数时记录这次调用
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Stack = append(err.Stack, object.Frame{Function: fn.Name, Pos: callSite, Args: len(args) + len(named)})
+		}
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
+		if len(named) > 0 {
+			err := newError("builtin functions do not accept named arguments")
+			err.Pos = callSite
+			return err
+		}
 		return fn.Fn(args...)
 	default:
 		return newError("not a function: %s", fn.Type())
@@ -188,11 +373,52 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 	}
 }
 
+// namedArgument 按参数名传递的参数 f(y: 2)
+type namedArgument struct {
+	name  string
+	value object.Object
+}
+
+// evalArguments 计算调用参数，展开 ...arr，按参数名传递的参数单独返回
+func evalArguments(exps []ast.Expression, env *object.Environment) ([]object.Object, []namedArgument, object.Object) {
+	var args []object.Object
+	var named []namedArgument
+	for _, e := range exps {
+		switch e := e.(type) {
+		case *ast.SpreadExpression:
+			value := Eval(e.Value, env)
+			if isInterrupted(value) {
+				return nil, nil, value
+			}
+			arr, ok := value.(*object.Array)
+			if !ok {
+				err := newError("cannot spread %s, expected ARRAY", value.Type())
+				err.Pos = e.Pos()
+				return nil, nil, err
+			}
+			args = append(args, arr.Elements...)
+		case *ast.NamedArgument:
+			value := Eval(e.Value, env)
+			if isInterrupted(value) {
+				return nil, nil, value
+			}
+			named = append(named, namedArgument{name: e.Name.Value, value: value})
+		default:
+			value := Eval(e, env)
+			if isInterrupted(value) {
+				return nil, nil, value
+			}
+			args = append(args, value)
+		}
+	}
+	return args, named, nil
+}
+
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 	for _, e := range exps {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isInterrupted(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -216,9 +442,177 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// isInterrupted 值是错误，或者是从if、match的块中传出的break、continue、return时为true。
+// 这时表达式不能继续计算，需要把这个值原样向上传递
+func isInterrupted(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ, object.RETURN_VALUE_OBJ:
+		return true
+	}
+	return false
+}
+
+// evalAssignExpression 给变量、数组元素或者哈希表的值赋值，复合赋值先用原来的值和右边计算出新的值
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.IndexExpression:
+		container := Eval(target.Left, env)
+		if isInterrupted(container) {
+			return container
+		}
+		index := Eval(target.Index, env)
+		if isInterrupted(index) {
+			return index
+		}
+		return evalIndexAssignment(node, container, index, env)
+	case *ast.DotExpression:
+		container := Eval(target.Left, env)
+		if isInterrupted(container) {
+			return container
+		}
+		if container.Type() != object.HASH_OBJ {
+			return newError("cannot set field %s on %s", target.Name.Value, container.Type())
+		}
+		return evalIndexAssignment(node, container, &object.String{Value: target.Name.Value}, env)
+	}
+
+	name := node.Target.(*ast.Identifier).Value
+	var current object.Object
+	if node.Operator != "=" {
+		var ok bool
+		if current, ok = env.Get(name); !ok {
+			return newError("cannot assign to undeclared variable %s", name)
+		}
+	}
+	value := evalAssignedValue(node, current, env)
+	if isInterrupted(value) {
+		return value
+	}
+	switch env.Assign(name, value) {
+	case object.ErrUndeclared:
+		return newError("cannot assign to undeclared variable %s", name)
+	case object.ErrConstant:
+		return newError("cannot assign to constant %s", name)
+	}
+	return value
+}
+
+// evalIndexAssignment 原地修改数组的元素或者哈希表的值，数组的下标必须在范围内
+func evalIndexAssignment(node *ast.AssignExpression, container, index object.Object, env *object.Environment) object.Object {
+	switch container := container.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		switch {
+		case !ok && index.Type() == object.INTEGER_OBJ:
+			// 大整数一定超出了范围
+			return newError("index out of range: %s (array length %d)", index.Inspect(), len(container.Elements))
+		case !ok:
+			return newError("array index must be INTEGER, got %s", index.Type())
+		case i.Value < 0 || i.Value >= int64(len(container.Elements)):
+			return newError("index out of range: %d (array length %d)", i.Value, len(container.Elements))
+		}
+		value := evalAssignedValue(node, container.Elements[i.Value], env)
+		if isInterrupted(value) {
+			return value
+		}
+		container.Elements[i.Value] = value
+		return value
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		var current object.Object = NULL
+		if pair, ok := container.Pairs[key.HashKey()]; ok {
+			current = pair.Value
+		}
+		value := evalAssignedValue(node, current, env)
+		if isInterrupted(value) {
+			return value
+		}
+		container.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+		return value
+	default:
+		return newError("index assignment not supported: %s", container.Type())
+	}
+}
+
+// evalAssignedValue 计算赋值表达式右边的值，复合赋值时再和当前的值current运算
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isInterrupted(value) || node.Operator == "=" {
+		return value
+	}
+	return evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, value, env.CheckedArithmetic())
+}
+
+// evalChain 计算 a.b[c]?.d.f() 这样的访问链。?. 或者 ?[ 左边的值是null时跳过链中剩下的访问，
+// 整个链的结果是null，这时skipped为true
+func evalChain(node ast.Expression, env *object.Environment) (result object.Object, skipped bool) {
+	var left ast.Expression
+	var optional bool
+	switch node := node.(type) {
+	case *ast.DotExpression:
+		left, optional = node.Left, node.Optional
+	case *ast.IndexExpression:
+		left, optional = node.Left, node.Optional
+	case *ast.MethodCallExpression:
+		left, optional = node.Object, node.Optional
+	default:
+		return Eval(node, env), false
+	}
+	container, skipped := evalChain(left, env)
+	if skipped || isInterrupted(container) {
+		return container, skipped
+	}
+	if optional && container == NULL {
+		return NULL, true
+	}
+	switch node := node.(type) {
+	case *ast.DotExpression:
+		return evalDotExpression(container, node.Name.Value), false
+	case *ast.MethodCallExpression:
+		return evalMethodCall(node, container, env), false
+	default:
+		index := Eval(node.(*ast.IndexExpression).Index, env)
+		if isInterrupted(index) {
+			return index, false
+		}
+		return evalIndexExpression(container, index), false
+	}
+}
+
+// evalDotExpression 读取哈希表中名字为name的字段，没有这个字段时返回null
+func evalDotExpression(left object.Object, name string) object.Object {
+	hash, ok := left.(*object.Hash)
+	if !ok {
+		return newError("cannot access field %s on %s", name, left.Type())
+	}
+	pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]
+	if !ok {
+		return NULL
+	}
+	return pair.Value
+}
+
+// evalConditionalExpression 计算 cond ? a : b，按照if的规则判断条件
+func evalConditionalExpression(node *ast.ConditionalExpression, env *object.Environment) object.Object {
+	condition := Eval(node.Condition, env)
+	if isInterrupted(condition) {
+		return condition
+	}
+	if isTruthy(condition) {
+		return Eval(node.Consequence, env)
+	}
+	return Eval(node.Alternative, env)
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isInterrupted(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
@@ -229,6 +623,34 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	return NULL
 }
 
+// evalLogicalExpression 计算 && 和 ||，左边已经能决定结果时不计算右边，结果总是布尔值
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isInterrupted(left) {
+		return left
+	}
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+	right := Eval(node.Right, env)
+	if isInterrupted(right) {
+		return right
+	}
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+// evalNullishExpression 计算 a ?? b，a不是null时结果是a，不计算b
+func evalNullishExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isInterrupted(left) || left != NULL {
+		return left
+	}
+	return Eval(node.Right, env)
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -242,10 +664,12 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func evalInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, checked)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -268,22 +692,37 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	return &object.String{Value: leftVal + rightVal}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+// evalFloatInfixExpression 至少有一边是浮点数时，整数转换成浮点数后再计算
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	if (operator == "/" || operator == "%") && rightVal == 0 {
+		return divisionByZeroError(operator)
+	}
+	if operator == "**" && leftVal == 0 && rightVal < 0 {
+		return divisionByZeroError(operator)
+	}
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return &object.Float{Value: leftVal + rightVal}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return &object.Float{Value: leftVal - rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: leftVal / rightVal}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return &object.Float{Value: leftVal * rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	case "==":
@@ -293,6 +732,26 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+// isNumber 检查对象是否是整数或者浮点数
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat 把整数或者浮点数转换成float64
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
 func nativeBoolToBooleanObject(value bool) object.Object {
 	if value {
 		return TRUE
@@ -300,24 +759,31 @@ func nativeBoolToBooleanObject(value bool) object.Object {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(operator string, right object.Object, checked bool) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusOperatorExpression(right)
+		return evalMinusOperatorExpression(right, checked)
+	case "~":
+		if right.Type() != object.INTEGER_OBJ {
+			return newError("unknown operator: ~%s", right.Type())
+		}
+		return complementInteger(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-func evalMinusOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+func evalMinusOperatorExpression(right object.Object, checked bool) object.Object {
+	switch right := right.(type) {
+	case *object.Integer, *object.BigInt:
+		return negateInteger(right, checked)
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -347,6 +813,7 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
+// evalBlockStatement 执行块中的语句，块的值是最后一条语句的值。空的块或者最后一条语句没有值时是null
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -354,14 +821,126 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		result = Eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
 	}
+	if result == nil {
+		return NULL
+	}
 	return result
 }
 
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isInterrupted(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+		if result, done := evalLoopBody(node.Body, env); done {
+			return result
+		}
+	}
+}
+
+// evalForStatement 执行C风格的循环，init中声明的变量只在循环中可见
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+	if node.Init != nil {
+		if init := Eval(node.Init, loopEnv); isInterrupted(init) {
+			return init
+		}
+	}
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, loopEnv)
+			if isInterrupted(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NULL
+			}
+		}
+		if result, done := evalLoopBody(node.Body, loopEnv); done {
+			return result
+		}
+		if node.Update != nil {
+			if update := Eval(node.Update, loopEnv); isInterrupted(update) {
+				return update
+			}
+		}
+	}
+}
+
+// evalForInStatement 用集合的迭代器遍历集合，循环变量只在循环中可见
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isInterrupted(iterable) {
+		return iterable
+	}
+	it, iterErr := iteratorOf(iterable, node.Iterable.Pos())
+	if iterErr != nil {
+		if err, ok := iterErr.(*object.Error); ok && !err.Pos.IsValid() {
+			err.Pos = node.Iterable.Pos()
+		}
+		return iterErr
+	}
+	loopEnv := object.NewEnclosedEnvironment(env)
+	for {
+		key, value, ok := it.Next()
+		if !ok {
+			if script, ok := it.(*scriptIterator); ok && script.err != nil {
+				return script.err
+			}
+			return NULL
+		}
+		if node.Key != nil {
+			loopEnv.Set(node.Key.Value, key)
+		}
+		loopEnv.Set(node.Value.Value, value)
+		if result, done := evalLoopBody(node.Body, loopEnv); done {
+			return result
+		}
+	}
+}
+
+// evalLoopBody 执行一次循环体，done为true时循环结束，result是循环的结果：
+// break时为null，return和错误时原样向外传递
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (result object.Object, done bool) {
+	switch result := Eval(body, env).(type) {
+	case *object.Break:
+		return NULL, true
+	case *object.ReturnValue, *object.Error:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+	for _, part := range node.Parts {
+		value := Eval(part, env)
+		if isInterrupted(value) {
+			return value
+		}
+		out.WriteString(stringify(value))
+	}
+	return &object.String{Value: out.String()}
+}
+
+// stringify 把值转换成字符串，插值字符串和str都使用它，没有值时当作null
+func stringify(obj object.Object) string {
+	if obj == nil {
+		obj = NULL
+	}
+	return obj.Inspect()
+}
+
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -27,6 +27,30 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"0xFF", 255},
+		{"0Xff + 0o17", 270},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_7fff_ffff_ffff_ffff", 9223372036854775807},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"0 ** 0", 1},
+		{"(-1) ** 1000000000000", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 10", 1024},
+		{"-1024 >> 3", -128},
+		{"1 >> 100", 0},
+		{"-1 >> 100", -1},
+		{"1 + 2 << 3", 24},
+		{"(1 << 65) % 1000", 232},
+		{"(1 << 64) & 0xff", 0},
 	}
 
 	for _, tt := range tests {
@@ -56,6 +80,147 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalBigIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4611686018427387904 * 4", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"0xffff_ffff_ffff_ffff", "18446744073709551615"},
+		{"2 ** 64", "18446744073709551616"},
+		{"3 ** 50", "717897987691852588770249"},
+		{"1 << 64", "18446744073709551616"},
+		{"(1 << 100) >> 36", "18446744073709551616"},
+		{"~(1 << 64)", "-18446744073709551617"},
+		{"(1 << 64) | 1", "18446744073709551617"},
+		{"99999999999999999999 / 3", "33333333333333333333"},
+		{"-99999999999999999999 / 7", "-14285714285714285714"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", "15511210043330985984000000"},
+		{`int("123456789012345678901234567890")`, "123456789012345678901234567890"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		result, ok := evaluated.(*object.BigInt)
+		if !ok {
+			t.Errorf("%s: obj is not *object.BigInt. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. expected=%s, got=%s", tt.input, tt.expected, result.Inspect())
+		}
+		if result.Type() != object.INTEGER_OBJ {
+			t.Errorf("%s: wrong type. got=%s", tt.input, result.Type())
+		}
+	}
+
+	// 结果能放进int64时转换回Integer
+	testIntegerObject(t, testEval("99999999999999999999 - 99999999999999999990"), 9)
+	testIntegerObject(t, testEval("(9223372036854775807 + 1) / 2"), 4611686018427387904)
+	testBooleanObject(t, testEval("99999999999999999999 > 9223372036854775807"), true)
+	testBooleanObject(t, testEval("99999999999999999999 == 99999999999999999998 + 1"), true)
+	testFloatObject(t, testEval("99999999999999999999 * 1.0"), 1e20)
+	testNullObject(t, testEval("[1, 2][99999999999999999999]"))
+	testIntegerObject(t, testEval(`{99999999999999999999: 1}[99999999999999999998 + 1]`), 1)
+	// 大整数的键和哈希值相同的int64的键互不影响
+	testNullObject(t, testEval(`{(1 << 70): 1}[-1282213404852117905]`))
+	testIntegerObject(t, testEval(`let h = {-1282213404852117905: 1}; h[1 << 70] = 2; h[-1282213404852117905]`), 1)
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	testEvalChecked := func(input string) object.Object {
+		env := object.NewEnvironment()
+		env.SetCheckedArithmetic(true)
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"2 ** 63", "integer overflow: 2 ** 63"},
+		{"1 << 63", "integer overflow: 1 << 63"},
+		{"-(-9223372036854775807 - 1)", "integer overflow: -(-9223372036854775808)"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", "integer overflow: 21 * 2432902008176640000"},
+		{"let a = 9223372036854775807; a += 1", "integer overflow: 9223372036854775807 + 1"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEvalChecked(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: expected error", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("%s: wrong error message. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+	}
+
+	// 没有溢出的运算不受影响
+	testIntegerObject(t, testEvalChecked("9223372036854775806 + 1"), 9223372036854775807)
+	testIntegerObject(t, testEvalChecked("2 ** 62"), 4611686018427387904)
+
+	// 设置只影响这个环境，其它环境溢出时仍然转换成大整数
+	if _, ok := testEval("9223372036854775807 + 1").(*object.BigInt); !ok {
+		t.Errorf("unchecked environment should not report overflow")
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"0.1 + 0.2", 0.30000000000000004},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"1e3 - 1", 999},
+		{"1_000.5", 1000.5},
+		{"7.5 % 2", 1.5},
+		{"2 ** 0.5 ** 2", 1.189207115002721},
+		{"4 ** 0.5", 2},
+		{"2 ** -1", 0.5},
+		{"2.0 ** 3", 8},
+		{"float(7) / 2", 3.5},
+		{`float("2.25")`, 2.25},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+
+	// 整数之间的除法仍然是整数除法
+	testIntegerObject(t, testEval("7 / 2"), 3)
+	testIntegerObject(t, testEval("int(3.99)"), 3)
+	testIntegerObject(t, testEval("int(-3.99)"), -3)
+	testIntegerObject(t, testEval(`int("42")`), 42)
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("obj is not *object.Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object.Float got wrong value, expected=%g, got=%g.", expected, result.Value)
+		return false
+	}
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -64,6 +229,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"true", true},
 		{"false", false},
 		{"1 < 2", true},
+		{"1 < 1.5", true},
+		{"2.5 > 3", false},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"1.5 != 1.5", false},
 		{"1 > 2", false},
 		{"1 < 1", false},
 		{"1 > 1", false},
@@ -80,6 +250,23 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1.5 <= 1", false},
+		{"99999999999999999999 >= 1", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && \"\"", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		// 左边能决定结果时不计算右边
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"false && -true", false},
 	}
 
 	for _, tt := range tests {
@@ -150,6 +337,214 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; if (x < 3) { 1 } else if (x < 10) { 2 } else { 3 }", "1"},
+		{"let x = 5; if (x < 3) { 1 } else if (x < 10) { 2 } else { 3 }", "2"},
+		{"let x = 50; if (x < 3) { 1 } else if (x < 10) { 2 } else { 3 }", "3"},
+		{"let x = 50; if (x < 3) { 1 } else if (x < 10) { 2 }", "null"},
+		{"let x = 7; if (x == 1) { 1 } else if (x == 2) { 2 } else if (x == 7) { 7 } else { 0 }", "7"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"true ? 1 : 2", "1"},
+		{"false ? 1 : 2", "2"},
+		{"let x = 0; x > 0 ? \"positive\" : x < 0 ? \"negative\" : \"zero\"", "zero"},
+		{"let h = {}; h.k ? 1 : 2", "2"},
+		{"let n = 0; true ? 1 : n = 5; n", "0"},
+		{"let f = fn(n) { n <= 1 ? 1 : n * f(n - 1) }; f(5)", "120"},
+		{"let h = {\"a\": 1}; h.a ?? 0", "1"},
+		{"let h = {}; h.a ?? 0", "0"},
+		{"let h = {\"a\": false}; h.a ?? true", "false"},
+		{"let h = {}; h.a ?? h.b ?? 3", "3"},
+		{"let n = 0; 1 ?? (n = 5); n", "0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"a": {"b": 1}}; h?.a?.b`, "1"},
+		{`let h = {}; h.a?.b`, "null"},
+		{`let h = {}; h.a?.b.c[0]`, "null"},
+		{`let h = {}; h.a?[0]`, "null"},
+		{`let a = [[1, 2]]; a[0]?[1]`, "2"},
+		{`let h = {"xs": [{"name": "x"}]}; h.xs?[0]?.name`, "x"},
+		{`let h = {}; h.user?.name ?? "anonymous"`, "anonymous"},
+		{`let n = 0; let f = fn() { n += 1 }; let h = {}; h.a?[f()]; n`, "0"},
+		{`let c = true; c ?[1] : [2]`, "[1]"},
+		{`let c = false; c ?[1] : [2]`, "[2]"},
+		{`let h = {"a": [1]}; {h?["a"][0]: 2}`, "{1: 2}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	describe := `let describe = fn(v) {
+	match (v) {
+		0 => "zero",
+		-1 => "minus one",
+		"hi" => "greeting",
+		true => "yes",
+		[] => "empty",
+		[a, b] => "pair ${a} ${b}",
+		[first, ...rest] if len(rest) > 2 => "long from ${first}",
+		{"kind": "circle", "r": r} => "circle ${r}",
+		{"kind": "square"} => "square",
+		_ => "other",
+	}
+};`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"describe(0)", "zero"},
+		{"describe(0.0)", "zero"},
+		{"describe(-1)", "minus one"},
+		{`describe("hi")`, "greeting"},
+		{`describe("hello")`, "other"},
+		{"describe(true)", "yes"},
+		{"describe(1 == 1)", "yes"},
+		{"describe([])", "empty"},
+		{"describe([1, 2])", "pair 1 2"},
+		{"describe([1, 2, 3])", "other"},
+		{"describe([1, 2, 3, 4])", "long from 1"},
+		{`describe({"kind": "circle", "r": 2, "x": 1})`, "circle 2"},
+		{`describe({"kind": "square"})`, "square"},
+		{`describe({"kind": "triangle"})`, "other"},
+		{`describe({"r": 1})`, "other"},
+		{"describe(5)", "other"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(describe + tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMatchBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", "6"},
+		{"let x = 1; match (5) { x => x }; x", "1"},
+		{"let x = 1; match ([5, 6]) { [x, 7] => x, _ => x }", "1"},
+		{"let n = 0; match (1) { _ => { n = 10 } }; n", "10"},
+		{"let f = fn(x) { match (x) { 0 => { return 100 } _ => 1 }; 2 }; [f(0), f(1)]", "[100, 2]"},
+		{"let size = fn(n) { match (n) { n if n > 100 => { let s = \"big\"; s }, n if n > 10 => \"medium\", _ => \"small\" } }; [size(1000), size(50), size(1)]", "[big, medium, small]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1, 2]; a + b", "3"},
+		{"let [a, b, ...rest] = [1, 2, 3, 4]; [a, b, rest]", "[1, 2, [3, 4]]"},
+		{"let [a, ...rest] = [1]; rest", "[]"},
+		{"let [a, _, c] = [1, 2, 3]; c", "3"},
+		{"let [a, b = 10] = [1]; [a, b]", "[1, 10]"},
+		{"let [a, b = a * 2] = [4]; b", "8"},
+		{"let [a, b = 10] = [1, 2]; b", "2"},
+		{`let {name, age} = {"name": "bob", "age": 3}; age`, "3"},
+		{`let {"n": x, 1: y} = {"n": 5, 1: 6}; x + y`, "11"},
+		{`let {name, age = 18} = {"name": "bob"}; age`, "18"},
+		{`let {"pos": [x, y], "tags": [first, ...others]} = {"pos": [1, 2], "tags": ["a", "b", "c"]}; [x, y, first, others]`, "[1, 2, a, [b, c]]"},
+		{`let [{name}, [n]] = [{"name": "x"}, [7]]; n`, "7"},
+		{"let [a, b] = [1, 2]; let [a, b] = [b, a]; [a, b]", "[2, 1]"},
+		{"let f = fn() { [1, 2] }; let [x, y] = f(); x * 10 + y", "12"},
+		{"let f = fn([a, b]) { a + b }; f([1, 2])", "3"},
+		{`let f = fn({name, age = 1}) { age }; f({"name": "x"})`, "1"},
+		{"let f = fn(x, [a, b] = [x, x]) { a + b }; f(3)", "6"},
+		{"let f = fn([a, b], ...rest) { [a, b, rest] }; f([1, 2], 3)", "[1, 2, [3]]"},
+		{"match ([1]) { [a, b = 5] => a + b }", "6"},
+		{`match ({"a": 1}) { {a, b = 2} => a + b }`, "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestConstDestructuring(t *testing.T) {
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New("const [a, {b}] = [1, {\"b\": 2}];")).ParseProgram()
+	Eval(program, env)
+	for _, name := range []string{"a", "b"} {
+		if !env.IsConst(name) {
+			t.Errorf("%s is not constant", name)
+		}
+	}
+}
+
+func TestFailedDestructuringBindsNothing(t *testing.T) {
+	tests := []string{
+		"const [a, [b, c]] = [1, 2];",
+		`let [a, {"k": b}] = [1, {}];`,
+		"let [a, b = undefined] = [1];",
+	}
+
+	for _, input := range tests {
+		env := object.NewEnvironment()
+		result := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+		if _, ok := result.(*object.Error); !ok {
+			t.Errorf("%q: expected error, got=%s", input, result.Inspect())
+			continue
+		}
+		if value, ok := env.Get("a"); ok {
+			t.Errorf("%q: a is bound to %s after the error", input, value.Inspect())
+		}
+		// 在REPL中可以继续声明同名的变量
+		again := Eval(parser.New(lexer.New("let a = 5; a")).ParseProgram(), env)
+		testIntegerObject(t, again, 5)
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -185,6 +580,82 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -BOOLEAN",
 		},
+		{
+			"~1.5",
+			"unknown operator: ~FLOAT",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"1 % 0",
+			"modulo by zero",
+		},
+		{
+			"99999999999999999999 / 0",
+			"division by zero",
+		},
+		{
+			"99999999999999999999 % (1 - 1)",
+			"modulo by zero",
+		},
+		{
+			"1.5 / 0",
+			"division by zero",
+		},
+		{
+			"1 / 0.0",
+			"division by zero",
+		},
+		{
+			"2.5 % 0",
+			"modulo by zero",
+		},
+		{
+			"0 ** -1",
+			"division by zero",
+		},
+		{
+			"0 ** -99999999999999999999",
+			"division by zero",
+		},
+		{
+			"0.0 ** -0.5",
+			"division by zero",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"true && -true",
+			"unknown operator: -BOOLEAN",
+		},
+		{
+			"2 ** 100000000000",
+			"integer result too large: 2 ** 100000000000",
+		},
+		{
+			"2 ** 9223372036854775807",
+			"integer result too large: 2 ** 9223372036854775807",
+		},
+		{
+			"3 ** 4611686018427387904",
+			"integer result too large: 3 ** 4611686018427387904",
+		},
+		{
+			"1 << 9223372036854775807",
+			"integer result too large: 1 << 9223372036854775807",
+		},
+		{
+			"(1 << 100) << 9223372036854775800",
+			"integer result too large: 1267650600228229401496703205376 << 9223372036854775800",
+		},
 		{
 			"true + false;",
 			"unknown operator: BOOLEAN + BOOLEAN",
@@ -254,6 +725,238 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1; a = 2; a", "2"},
+		{"let a = 1; a = 2", "2"},
+		{"let a = 1; let b = 2; a = b = 3; [a, b]", "[3, 3]"},
+		{"let a = 10; a += 5; a -= 3; a *= 2; a /= 4; a", "6"},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let f = 1.5; f *= 2; f", "3.0"},
+		{"let a = 1; let set = fn() { a = 2 }; set(); a", "2"},
+		{"let a = 1; let shadow = fn() { let a = 5; a = 6; a }; [shadow(), a]", "[6, 1]"},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", "3"},
+		{"const k = 1; let f = fn() { let k = 2; k = 3; k }; [f(), k]", "[3, 1]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a", "[10, 2, 3]"},
+		{"let a = [1, 2, 3]; a[2] = 5", "5"},
+		{"let a = [1, 2, 3]; a[1] += 5; a[2] *= a[1]; a", "[1, 7, 21]"},
+		{"let a = [[1], [2]]; a[1][0] = 3; a", "[[1], [3]]"},
+		{"let a = [1]; let b = a; b[0] = 2; a", "[2]"},
+		{"let a = [0, 0]; let set = fn(i, v) { a[i] = v }; set(1, 9); a", "[0, 9]"},
+		{`let h = {}; h["k"] = 1; h["k"]`, "1"},
+		{`let h = {"k": 1}; h["k"] += 1; h["k"]`, "2"},
+		{`let h = {}; h[1] = "one"; h[true] = "yes"; [h[1], h[true]]`, "[one, yes]"},
+		{`let h = {}; h.name = "bubble"; h["name"]`, "bubble"},
+		{`let h = {"n": 1}; h.n -= 3; h.n`, "-2"},
+		{`let h = {"inner": {}}; h.inner.k = 1; h.inner["k"]`, "1"},
+		{`let h = {"k": 1}; h.missing`, "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let n = 0; while (n < 5) { n += 1 }; n", "5"},
+		{"let n = 0; while (false) { n += 1 }; n", "0"},
+		{"while (false) { 1 }", "null"},
+		{"let s = 0; for (let i = 1; i <= 100; i += 1) { s += i }; s", "5050"},
+		{"let s = 0; for (let i = 0; i < 10; i += 1) { if (i % 2 == 0) { continue } s += i }; s", "25"},
+		{"let n = 0; for (;;) { n += 1; if (n == 7) { break } }; n", "7"},
+		{"let n = 0; while (true) { n += 1; if (n > 3) { break; } }; n", "4"},
+		{"let i = 10; for (let i = 0; i < 3; i += 1) { }; i", "10"},
+		{"let i = 0; for (i = 0; i < 3; i += 1) { }; i", "3"},
+		{"let count = 0; for (let i = 0; i < 3; i += 1) { for (let j = 0; j < 3; j += 1) { if (j == 1) { break } count += 1 } }; count", "3"},
+		{"let find = fn(xs, x) { for (let i = 0; i < len(xs); i += 1) { if (xs[i] == x) { return i } }; -1 }; [find([5, 6, 7], 7), find([1], 2)]", "[2, -1]"},
+		{"let fs = []; for (let i = 0; i < 3; i += 1) { fs = push(fs, fn() { i }) }; fs[0]()", "3"},
+		{"let n = 0; while (n < 200000) { n += 1 }; n", "200000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestLoopControlInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let i = 0; while (true) { i += 1; let y = if (i > 3) { break; } else { 1 }; }; i", "4"},
+		{"let r = 1; while (true) { const y = if (true) { break; } else { 1 }; r = 2 }; r", "1"},
+		{"let i = 0; let out = []; while (i < 5) { i += 1; out = push(out, if (i > 2) { break; } else { i }); }; out", "[1, 2]"},
+		{"let r = 0; for (x in [1, 2, 3]) { r = match (x) { 2 => { break; }, _ => x } }; r", "1"},
+		{"let s = 0; for (x in [1, 2, 3]) { s += if (x == 2) { continue } else { x } }; s", "4"},
+		{"let out = []; for (x in [1, 2, 3]) { out = push(out, [x, if (x == 2) { continue } else { x }]) }; out", "[[1, 1], [3, 3]]"},
+		{`let h = {}; for (x in [1, 2]) { h = {"k": if (x == 2) { break } else { x }} }; h.k`, "1"},
+		{"let n = 0; for (x in [1, 2, 3]) { n = 10 + if (x == 3) { break } else { x } }; n", "12"},
+		{"let n = 0; for (x in [1, 2, 3]) { n = -if (x == 3) { break } else { x } }; n", "-2"},
+		{"let n = 0; for (x in [1, 2, 3]) { if (if (x == 2) { break } else { true }) { n += x } }; n", "1"},
+		{"let n = 0; for (x in [1, 2, 3]) { let [a] = [if (x == 2) { break } else { x }]; n = a }; n", "1"},
+		{"let f = fn() { let y = if (true) { return 5 } else { 1 }; 10 }; f()", "5"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestForInLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let s = 0; for (x in [1, 2, 3]) { s += x }; s", "6"},
+		{"let out = []; for (i, x in [5, 6]) { out = push(out, [i, x]) }; out", "[[0, 5], [1, 6]]"},
+		{`let out = []; for (k, v in {"b": 2, "a": 1}) { out = push(out, k + str(v)) }; out`, "[a1, b2]"},
+		{`let out = []; for (v in {"b": 2, "a": 1}) { out = push(out, v) }; out`, "[1, 2]"},
+		{`let out = []; for (ch in "héllo") { out = push(out, ch) }; out`, "[h, é, l, l, o]"},
+		{`let out = []; for (i, ch in "ab") { out = push(out, i) }; out`, "[0, 1]"},
+		{"let out = []; for (i in range(3)) { out = push(out, i) }; out", "[0, 1, 2]"},
+		{"let out = []; for (i in range(0, 10, 3)) { out = push(out, i) }; out", "[0, 3, 6, 9]"},
+		{"let out = []; for (i in range(3, 0, -1)) { out = push(out, i) }; out", "[3, 2, 1]"},
+		{"let n = 0; for (i in range(5, 0)) { n += 1 }; n", "0"},
+		{"let s = 0; for (i in range(100000)) { s += i }; s", "4999950000"},
+		{"let s = 0; for (x in [1, 2, 3, 4]) { if (x == 2) { continue } if (x == 4) { break } s += x }; s", "4"},
+		{"let x = 10; for (x in [1, 2]) { }; x", "10"},
+		{"let a = [1, 2, 3]; for (i, x in a) { a[i] = x * x }; a", "[1, 4, 9]"},
+		{"let first = fn(xs) { for (x in xs) { return x } }; first([7, 8])", "7"},
+		{"for (x in []) { x }", "null"},
+		{"range(1, 10, 2)", "range(1, 10, 2)"},
+		// 脚本中的集合通过iter和next方法实现迭代协议
+		{`let c = {"items": [1, 2, 3], "iter": fn() { self.items }}; let s = 0; for (x in c) { s += x }; s`, "6"},
+		{`let c = {"n": 3, "iter": fn() { range(self.n) }}; let out = []; for (x in c) { out = push(out, x) }; out`, "[0, 1, 2]"},
+		{"let counter = fn(n) { let i = 0; {\"next\": fn() { if (i < n) { i += 1; i } }} }; let out = []; for (k, v in counter(3)) { out = push(out, [k, v]) }; out", "[[0, 1], [1, 2], [2, 3]]"},
+		{"let counter = fn(n) { let i = 0; {\"next\": fn() { if (i < n) { i += 1; i } }} }; let c = {\"iter\": fn() { counter(2) }}; let s = 0; for (x in c) { s += x }; s", "3"},
+		{`let c = {"i": 0, "next": fn() { self.i += 1; if (self.i <= 2) { self.i } }}; let s = 0; for (x in c) { s += x }; s`, "3"},
+		{`let n = 0; for (k, v in {"next": 1, "iter": "x"}) { n += 1 }; n`, "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedPos     string
+	}{
+		{"x = 1", "cannot assign to undeclared variable x", "1:1"},
+		{"x += 1", "cannot assign to undeclared variable x", "1:1"},
+		{"const k = 1; k = 2", "cannot assign to constant k", "1:14"},
+		{"const k = 1; k += 2", "cannot assign to constant k", "1:14"},
+		{"const k = 1; let f = fn() { k = 2 }; f()", "cannot assign to constant k", "1:29"},
+		{"const k = 1; let k = 2;", "cannot redeclare constant k", "1:14"},
+		{"let a = 1; a += true", "type mismatch: INTEGER + BOOLEAN", "1:12"},
+		{"let a = 1; a /= 0", "division by zero", "1:12"},
+		{"let a = [1, 2]; a[2] = 3", "index out of range: 2 (array length 2)", "1:17"},
+		{"let a = [1, 2]; a[-1] = 3", "index out of range: -1 (array length 2)", "1:17"},
+		{"let a = [1]; a[99999999999999999999] = 3", "index out of range: 99999999999999999999 (array length 1)", "1:14"},
+		{`let a = [1]; a["0"] = 3`, "array index must be INTEGER, got STRING", "1:14"},
+		{"let h = {}; h[[1]] = 2", "unusable as hash key: ARRAY", "1:13"},
+		{"let h = {}; h[fn() {}] = 2", "unusable as hash key: FUNCTION", "1:13"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING", "1:16"},
+		{"let a = [1]; a.k = 2", "cannot set field k on ARRAY", "1:14"},
+		{"let a = [1]; a.k", "cannot access field k on ARRAY", "1:14"},
+		{"let h = {}; h.k += 1", "type mismatch: NULL + INTEGER", "1:13"},
+		{"while (x) { 1 }", "identifier not found: x", "1:8"},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER", "1:11"},
+		{`let c = {"iter": fn() { 1 }}; for (x in c) { x }`, "iter must return an iterable value or an iterator with next, got INTEGER", "1:41"},
+		{`let c = {"next": fn() { 1 / 0 }}; for (x in c) { x }`, "division by zero", "1:25"},
+		{"match (3) { 1 => 1, 2 => 2 }", "no match arm matched 3", "1:1"},
+		{`let x = match ("z") { "a" => 1 }`, `no match arm matched "z"`, "1:9"},
+		{"match (3) { n if n > 5 => 1 }", "no match arm matched 3", "1:1"},
+		{"match (3) { n if n + true => 1 }", "type mismatch: INTEGER + BOOLEAN", "1:18"},
+		{"match (y) { _ => 1 }", "identifier not found: y", "1:8"},
+		{"for (x in range(3)) { x + true }", "type mismatch: INTEGER + BOOLEAN", "1:23"},
+		{"range()", "range expects 1 to 3 arguments, got 0", "1:1"},
+		{`range("a")`, "argument 1 to `range` must be INTEGER, got STRING", "1:1"},
+		{"range(0, 99999999999999999999)", "argument 2 to `range` does not fit in 64 bits: 99999999999999999999", "1:1"},
+		{"range(0, 10, 0)", "step of `range` must not be zero", "1:1"},
+		{"for (let i = 0; i < 3; j += 1) { }", "cannot assign to undeclared variable j", "1:24"},
+		{"for (let i = 0; i < 3; i += 1) { i + true }", "type mismatch: INTEGER + BOOLEAN", "1:34"},
+		{"let [a, b] = [1, 2, 3];", "cannot destructure: expected an array of 2 elements, got 3", "1:5"},
+		{"let [a, b = 1] = [];", "cannot destructure: expected an array of 1 to 2 elements, got 0", "1:5"},
+		{"let [a, b, ...c] = [1];", "cannot destructure: expected an array of at least 2 elements, got 1", "1:5"},
+		{"let [a, b] = 5;", "cannot destructure: expected ARRAY, got INTEGER", "1:5"},
+		{`let {name, age} = {"name": "x"};`, `cannot destructure: missing key "age"`, "1:12"},
+		{`let {"pos": [x, y]} = {"pos": [1]};`, "cannot destructure: expected an array of 2 elements, got 1", "1:13"},
+		{"let {name} = [1];", "cannot destructure: expected HASH, got ARRAY", "1:5"},
+		{"let [1, x] = [2, 3];", "cannot destructure: expected 1, got 2", "1:6"},
+		{"let [a, b = c] = [1];", "identifier not found: c", "1:13"},
+		{"const k = 1; let [a, k] = [1, 2];", "cannot redeclare constant k", "1:22"},
+		{"let f = fn([a, b]) { a }; f([1])", "f cannot destructure argument for parameter [a, b]: expected an array of 2 elements, got 1", "1:27"},
+		{"let f = fn({name}) { name }; f({})", `f cannot destructure argument for parameter {name}: missing key "name"`, "1:30"},
+		{"let f = fn([a, b]) { a }; f()", "f expects 1 argument, got 0", "1:27"},
+		{"true ? 1 + true : 2", "type mismatch: INTEGER + BOOLEAN", "1:8"},
+		{"x ? 1 : 2", "identifier not found: x", "1:1"},
+		{"let h = {\"a\": 1}; h?.a.b", "cannot access field b on INTEGER", "1:19"},
+		{"let a = [1]; a?.k", "cannot access field k on ARRAY", "1:14"},
+		{`"abc".foo()`, "STRING has no method foo", "1:1"},
+		{"let h = {}; h.foo()", "HASH has no method foo", "1:13"},
+		{`let h = {"k": 1}; h.k()`, "not a function: INTEGER", "1:19"},
+		{"let x = 5; x.abs()", "INTEGER has no method abs", "1:12"},
+		{`"abc".upper(1)`, "upper expects 0 arguments, got 1", "1:1"},
+		{`"a,b".split(1)`, "argument to `split` must be STRING, got INTEGER", "1:1"},
+		{"[1].map(1)", "argument to `map` must be FUNCTION or BUILTIN, got INTEGER", "1:1"},
+		{"[1].map(fn(x) { x + true })", "type mismatch: INTEGER + BOOLEAN", "1:17"},
+		{"[1].map(f: 1)", "builtin methods do not accept named arguments", "1:1"},
+		{"let h = {}; h.has([1])", "unusable as hash key: ARRAY", "1:13"},
+		{`let h = {"f": fn(x) { x }}; h.f()`, "f expects 1 argument, got 0", "1:29"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: expected error", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("%q: wrong error position. expected=%s, got=%s", tt.input, tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) {x+2; };"
 	evaluated := testEval(input)
@@ -292,6 +995,98 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", "11"},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", "3"},
+		{"let f = fn(x, y = x * 2) { y }; f(4)", "8"},
+		{"let n = 1; let f = fn(x = n) { x }; let n = 5; f()", "5"},
+		{"let f = fn(xs = []) { push(xs, 1) }; f(); f()", "[1]"},
+		{"let f = fn(...rest) { rest }; f()", "[]"},
+		{"let f = fn(x, ...rest) { rest }; f(1, 2, 3)", "[2, 3]"},
+		{"let f = fn(x, y = 2, ...rest) { [x, y, rest] }; f(1)", "[1, 2, []]"},
+		{"let f = fn(x, y = 2, ...rest) { [x, y, rest] }; f(1, 3, 5, 7)", "[1, 3, [5, 7]]"},
+		{"let add = fn(x, y, z) { x + y + z }; add(...[1, 2, 3])", "6"},
+		{"let add = fn(x, y, z) { x + y + z }; add(1, ...[2], ...[3])", "6"},
+		{"let f = fn(...rest) { len(rest) }; f(...[], ...[1, 2], 3)", "3"},
+		{"len(...[[1, 2]])", "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(x, y) { x - y }; f(y: 1, x: 3)", "2"},
+		{"let f = fn(x, y) { x - y }; f(3, y: 1)", "2"},
+		{"let f = fn(x, y = 2, z = 3) { [x, y, z] }; f(1, z: 30)", "[1, 2, 30]"},
+		{"let f = fn(x, y = x + 1) { [x, y] }; f(x: 5)", "[5, 6]"},
+		{"let f = fn(x, y = 0, ...rest) { [x, y, rest] }; f(...[1], y: 2)", "[1, 2, []]"},
+		// 空的函数体和没有值的块是null，作为参数传递时不会被当作没有传递
+		{"let f = fn(x) { x }; f(fn() {}())", "null"},
+		{"let f = fn(x) { x }; f(fn() { let a = 1; }())", "null"},
+		{"let f = fn(x, y = 2) { [x, y] }; f(1, y: fn() {}())", "[1, null]"},
+		{"let f = fn(x) { x }; f(x: if (true) {})", "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFunctionArity(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedPos     string
+	}{
+		{"let add = fn(x, y) { x + y; };\nadd(1)", "add expects 2 arguments, got 1", "2:1"},
+		{"let add = fn(x, y) { x + y; };\nadd(1, 2, 3)", "add expects 2 arguments, got 3", "2:1"},
+		{"let one = fn(x) { x };\n  one()", "one expects 1 argument, got 0", "2:3"},
+		{"let none = fn() { 1 }; none(1)", "none expects 0 arguments, got 1", "1:24"},
+		{"fn(x) { x }()", "<anonymous> expects 1 argument, got 0", "1:1"},
+		{"let f = fn(x, y = 1) { x };\nf()", "f expects 1 to 2 arguments, got 0", "2:1"},
+		{"let f = fn(x, y = 1) { x };\nf(1, 2, 3)", "f expects 1 to 2 arguments, got 3", "2:1"},
+		{"let f = fn(x, ...rest) { x };\nf()", "f expects at least 1 argument, got 0", "2:1"},
+		{"let f = fn(x, y) { x };\nf(1, z: 2)", "f has no parameter named z", "2:1"},
+		{"let f = fn(x, y) { x };\nf(1, x: 2)", "f got multiple values for parameter x", "2:1"},
+		{"let f = fn(x, y) { x };\nf(fn() {}(), x: 2)", "f got multiple values for parameter x", "2:1"},
+		{"let f = fn(x, y) { x };\nf(y: 2)", "f missing argument for parameter x", "2:1"},
+		{"let f = fn(x, ...rest) { x };\nf(rest: [1])", "f has no parameter named rest", "2:1"},
+		{"let f = fn(x) { x };\nf(...1)", "cannot spread INTEGER, expected ARRAY", "2:3"},
+		{"let f = fn(x = y) { x };\nf()", "identifier not found: y", "1:16"},
+		{`len(x: "a")`, "builtin functions do not accept named arguments", "1:1"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: expected error", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, tt.expectedMessage, errObj.Message)
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("%q: wrong error position. expected=%s, got=%s", tt.input, tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 let newAdder = fn(x) { fn(y) {x + y;};};
@@ -301,6 +1096,66 @@ addTwo(2);
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"double": fn(x) { x * 2 }}; h.double(4)`, "8"},
+		{`let counter = {"n": 0, "inc": fn(by = 1) { self.n += by; self.n }}; counter.inc(); counter.inc(by: 5)`, "6"},
+		{`let p = {"name": "bob", "greet": fn(greeting) { "${greeting}, ${self.name}" }}; p.greet("hi")`, "hi, bob"},
+		{`let h = {"f": fn() { self }}; h.f() == h`, "true"},
+		{`let self = 1; let h = {"f": fn() { self }}; h["f"]()`, "1"},
+		{`let h = {"len": fn() { 42 }}; h.len()`, "42"},
+		{`let h = {"a": 1, "b": 2}; h.len()`, "2"},
+		{`let h = {"b": 2, "a": 1}; [h.keys(), h.values()]`, "[[a, b], [1, 2]]"},
+		{`let h = {"a": 1}; [h.has("a"), h.has("b")]`, "[true, false]"},
+		{`let h = {"inner": {"f": fn() { self.v }, "v": 7}}; h.inner.f()`, "7"},
+		{`let h = {}; h.x?.f()`, "null"},
+		{`let h = {}; h.a?.f().g`, "null"},
+		{`let h = {"f": len}; h.f("abc")`, "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestBuiltinMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc".upper()`, "ABC"},
+		{`"ABC".lower()`, "abc"},
+		{`"  a b  ".trim()`, "a b"},
+		{`"héllo".len()`, "5"},
+		{`"a,b,c".split(",")`, "[a, b, c]"},
+		{`"bubble".contains("bb")`, "true"},
+		{`"bubble".contains("x")`, "false"},
+		{"[1, 2, 3].len()", "3"},
+		{"[1, 2].push(3)", "[1, 2, 3]"},
+		{"[1, 2, 3].map(fn(x) { x * x })", "[1, 4, 9]"},
+		{"[1, 2, 3].map(str)", "[1, 2, 3]"},
+		{"[1, 2, 3, 4].filter(fn(x) { x % 2 == 0 })", "[2, 4]"},
+		{"[1, 2, 3, 4].reduce(fn(acc, x) { acc + x }, 0)", "10"},
+		{"[].reduce(fn(acc, x) { acc + x }, 0)", "0"},
+		{`[1, "a", true].join("-")`, "1-a-true"},
+		{`[1, 2, 3].map(fn(x) { x * 10 }).filter(fn(x) { x > 10 }).join(", ")`, "20, 30"},
+		{"let f = fn(x) { if (x > 1) { return x } 0 }; [1, 2, 3].map(f)", "[0, 2, 3]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `let x = "hello, world!"; x;`
 	evaluated := testEval(input)
@@ -327,6 +1182,47 @@ func TestStringLiteralConcat(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let n = 4; "count: ${n + 1}"`, "count: 5"},
+		{`"${1}${2}"`, "12"},
+		{`let name = "bubble"; "hi ${name}!"`, "hi bubble!"},
+		{`"list ${[1, "a", true]} hash ${{"k": 1}["k"]}"`, "list [1, a, true] hash 1"},
+		{`let f = fn(x) { "<${x}>" }; "nested ${f("in ${1 + 1}")}"`, "nested <in 2>"},
+		{`"\${not} $5 {}"`, "${not} $5 {}"},
+		{`"count: " + str(5)`, "count: 5"},
+		{`str("s")`, "s"},
+		{`"${fn() {}()}"`, "null"},
+		{`"<${fn() { let a = 1; }()}>"`, "<null>"},
+		{`str(fn() {}())`, "null"},
+		{`str(if (false) { 1 })`, "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: got wrong type. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: got wrong string. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	if got := stringify(nil); got != "null" {
+		t.Errorf("stringify(nil) wrong. expected=%q, got=%q", "null", got)
+	}
+
+	errObj, ok := testEval(`"a ${-true} b"`).(*object.Error)
+	if !ok || errObj.Message != "unknown operator: -BOOLEAN" {
+		t.Errorf("expected error from interpolated expression, got=%+v", errObj)
+	}
+}
+
 func TestBuiltinFunction(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -335,8 +1231,26 @@ func TestBuiltinFunction(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
-		{`len(1)`, "argument to `len` not supported, got=INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len(1)`, "argument to `len` must be STRING or ARRAY, got INTEGER"},
+		{`len("one", "two")`, "len expects 1 argument, got 2"},
+		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`last("abc")`, "argument to `last` must be ARRAY, got STRING"},
+		{`rest()`, "rest expects 1 argument, got 0"},
+		{`push(1, 2)`, "argument 1 to `push` must be ARRAY, got INTEGER"},
+		{`push([1])`, "push expects 2 arguments, got 1"},
+		{`pop({})`, "argument to `pop` must be ARRAY, got HASH"},
+		{`str(1, 2)`, "str expects 1 argument, got 2"},
+		{`int(true)`, "argument to `int` must be INTEGER or FLOAT or STRING, got BOOLEAN"},
+		{`len("héllo")`, 5},
+		{`len("你好😀")`, 3},
+		{`len(bytes("héllo"))`, 6},
+		{`bytes("é")[1]`, 0xa9},
+		{`len(chars("你好😀"))`, 3},
+		{`ord("你")`, 0x4f60},
+		{`ord(chr(128512))`, 128512},
+		{`ord("ab")`, "argument to `ord` must be a single character, got \"ab\""},
+		{`chr(-1)`, "invalid code point for `chr`: -1"},
+		{`bytes(1)`, "argument to `bytes` must be STRING, got INTEGER"},
 	}
 
 	for _, tt := range tests {
@@ -377,6 +1291,37 @@ func TestArrayLiteral(t *testing.T) {
 
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"héllo"[1]`, "é"},
+		{`"你好😀"[2]`, "😀"},
+		{`let s = "名字"; s[0] + s[1]`, "名字"},
+		{`chars("你好")[1]`, "好"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("%s: wrong value. expected=%q, got=%q", tt.input, expected, str.Value)
+		}
+	}
+}
+
 func TestArrayIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -505,3 +1450,82 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPosition(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+	}{
+		{"foobar", "1:1"},
+		{"let a = 1;\nlet b = a + c;", "2:13"},
+		{"let a = 1;\n  a + true;", "2:3"},
+		{"if (true) {\n  -true\n}", "2:3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position. expected=%s, got=%s", tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+let outer = fn(x) {
+  add(x, "one")
+};
+let run = fn() { outer(1) };
+run();`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expectedFrames := []object.Frame{
+		{Function: "add", Args: 2},
+		{Function: "outer", Args: 1},
+		{Function: "run", Args: 0},
+	}
+	expectedPositions := []string{"5:3", "7:18", "8:1"}
+	if len(errObj.Stack) != len(expectedFrames) {
+		t.Fatalf("wrong number of frames. expected=%d, got=%d", len(expectedFrames), len(errObj.Stack))
+	}
+	for i, frame := range errObj.Stack {
+		if frame.Function != expectedFrames[i].Function || frame.Args != expectedFrames[i].Args {
+			t.Errorf("frames[%d] wrong. expected=%+v, got=%+v", i, expectedFrames[i], frame)
+		}
+		if frame.Pos.String() != expectedPositions[i] {
+			t.Errorf("frames[%d] wrong position. expected=%s, got=%s", i, expectedPositions[i], frame.Pos)
+		}
+	}
+
+	expected := `2:3: type mismatch: INTEGER + STRING
+    in add (2 arguments) called at 5:3
+    in outer (1 argument) called at 7:18
+    in run (0 arguments) called at 8:1`
+	if errObj.StackTrace() != expected {
+		t.Errorf("wrong stack trace. expected=\n%s\ngot=\n%s", expected, errObj.StackTrace())
+	}
+}
+
+func TestAnonymousFunctionStackTrace(t *testing.T) {
+	evaluated := testEval("fn(x) { -x }(true)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	expected := "1:9: unknown operator: -BOOLEAN\n    in <anonymous> (1 argument) called at 1:1"
+	if errObj.StackTrace() != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, errObj.StackTrace())
+	}
+}
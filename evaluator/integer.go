@@ -0,0 +1,261 @@
+package evaluator
+
+import (
+	"BubblePL/object"
+	"math"
+	"math/big"
+)
+
+// 整数运算先使用int64计算，溢出时自动转换成math/big计算，结果能放进int64时再转换回来
+
+// maxIntegerBits 乘方和左移结果的最大位数，避免一个表达式耗尽内存
+const maxIntegerBits = 1 << 24
+
+// evalIntegerInfixExpression 计算两个整数，checked为true时结果超出int64范围会报告溢出错误
+func evalIntegerInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
+	if (operator == "/" || operator == "%") && toBigInt(right).Sign() == 0 {
+		return divisionByZeroError(operator)
+	}
+	// 负数次方的结果是小数，0的负数次方相当于除以0
+	if operator == "**" && toBigInt(right).Sign() < 0 {
+		if toBigInt(left).Sign() == 0 {
+			return divisionByZeroError(operator)
+		}
+		return &object.Float{Value: math.Pow(toFloat(left), toFloat(right))}
+	}
+	l, lok := left.(*object.Integer)
+	r, rok := right.(*object.Integer)
+	if lok && rok {
+		if result, ok := evalSmallIntegerInfixExpression(operator, l.Value, r.Value); ok {
+			return result
+		}
+	}
+	result := evalBigIntegerInfixExpression(operator, toBigInt(left), toBigInt(right))
+	if _, ok := result.(*object.BigInt); ok && checked {
+		return newError("integer overflow: %s %s %s", left.Inspect(), operator, right.Inspect())
+	}
+	return result
+}
+
+// divisionByZeroError 除以0或者对0取模的错误
+func divisionByZeroError(operator string) *object.Error {
+	if operator == "%" {
+		return newError("modulo by zero")
+	}
+	return newError("division by zero")
+}
+
+// evalSmallIntegerInfixExpression 计算两个int64，结果溢出时返回false
+func evalSmallIntegerInfixExpression(operator string, leftVal, rightVal int64) (object.Object, bool) {
+	switch operator {
+	case "+":
+		sum := leftVal + rightVal
+		if (leftVal^sum)&(rightVal^sum) < 0 {
+			return nil, false
+		}
+		return &object.Integer{Value: sum}, true
+	case "-":
+		diff := leftVal - rightVal
+		if (leftVal^rightVal)&(leftVal^diff) < 0 {
+			return nil, false
+		}
+		return &object.Integer{Value: diff}, true
+	case "*":
+		product, ok := multiplyInt64(leftVal, rightVal)
+		if !ok {
+			return nil, false
+		}
+		return &object.Integer{Value: product}, true
+	case "/":
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return nil, false
+		}
+		return &object.Integer{Value: leftVal / rightVal}, true
+	case "%":
+		return &object.Integer{Value: leftVal % rightVal}, true
+	case "**":
+		power, ok := powerInt64(leftVal, rightVal)
+		if !ok {
+			return nil, false
+		}
+		return &object.Integer{Value: power}, true
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}, true
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}, true
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}, true
+	case "<<":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal), true
+		}
+		if leftVal == 0 {
+			return &object.Integer{Value: 0}, true
+		}
+		if rightVal >= 63 {
+			return nil, false
+		}
+		shifted := leftVal << rightVal
+		if shifted>>rightVal != leftVal {
+			return nil, false
+		}
+		return &object.Integer{Value: shifted}, true
+	case ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal), true
+		}
+		if rightVal >= 63 {
+			rightVal = 63
+		}
+		return &object.Integer{Value: leftVal >> rightVal}, true
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal), true
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal), true
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal), true
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal), true
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal), true
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal), true
+	default:
+		return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, operator, object.INTEGER_OBJ), true
+	}
+}
+
+func evalBigIntegerInfixExpression(operator string, leftVal, rightVal *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return newInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return newInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return newInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		// Quo和int64的除法一样向0取整
+		return newInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		return newInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		// 先除再比较，位数和指数相乘可能溢出int64
+		if leftVal.CmpAbs(big.NewInt(1)) > 0 && (!rightVal.IsInt64() || rightVal.Int64() > maxIntegerBits/int64(leftVal.BitLen())) {
+			return newError("integer result too large: %s ** %s", leftVal, rightVal)
+		}
+		return newInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "&":
+		return newInteger(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return newInteger(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return newInteger(new(big.Int).Xor(leftVal, rightVal))
+	case "<<":
+		if rightVal.Sign() < 0 {
+			return newError("negative shift count: %s", rightVal)
+		}
+		// 先减再比较，位数和移位数相加可能溢出int64
+		if leftVal.Sign() != 0 && (!rightVal.IsInt64() || rightVal.Int64() > maxIntegerBits-int64(leftVal.BitLen())) {
+			return newError("integer result too large: %s << %s", leftVal, rightVal)
+		}
+		return newInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Int64())))
+	case ">>":
+		if rightVal.Sign() < 0 {
+			return newError("negative shift count: %s", rightVal)
+		}
+		// 移出所有位之后结果是0或者-1
+		if !rightVal.IsInt64() || rightVal.Int64() > int64(leftVal.BitLen()) {
+			if leftVal.Sign() < 0 {
+				return &object.Integer{Value: -1}
+			}
+			return &object.Integer{Value: 0}
+		}
+		return newInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Int64())))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	default:
+		return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, operator, object.INTEGER_OBJ)
+	}
+}
+
+// newInteger 把big.Int转换成整数对象，能放进int64时使用object.Integer
+func newInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInt{Value: value}
+}
+
+// toBigInt 把整数对象转换成big.Int
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+// multiplyInt64 计算两个int64的乘积，溢出时返回false
+func multiplyInt64(leftVal, rightVal int64) (int64, bool) {
+	if leftVal == 0 || rightVal == 0 {
+		return 0, true
+	}
+	product := leftVal * rightVal
+	if product/rightVal != leftVal || leftVal == -1 && rightVal == math.MinInt64 || rightVal == -1 && leftVal == math.MinInt64 {
+		return 0, false
+	}
+	return product, true
+}
+
+// powerInt64 使用平方求幂计算base的exp次方，exp不能是负数，溢出时返回false
+func powerInt64(base, exp int64) (int64, bool) {
+	result := int64(1)
+	var ok bool
+	for exp > 0 {
+		if exp&1 == 1 {
+			if result, ok = multiplyInt64(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = multiplyInt64(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// complementInteger 按位取反，~x == -x - 1
+func complementInteger(obj object.Object) object.Object {
+	if i, ok := obj.(*object.Integer); ok {
+		return &object.Integer{Value: ^i.Value}
+	}
+	return newInteger(new(big.Int).Not(toBigInt(obj)))
+}
+
+// negateInteger 计算整数的相反数，-math.MinInt64 会转换成大整数，checked为true时报告溢出错误
+func negateInteger(obj object.Object, checked bool) object.Object {
+	if i, ok := obj.(*object.Integer); ok && i.Value != math.MinInt64 {
+		return &object.Integer{Value: -i.Value}
+	}
+	result := newInteger(new(big.Int).Neg(toBigInt(obj)))
+	if _, ok := result.(*object.BigInt); ok && checked {
+		return newError("integer overflow: -(%s)", obj.Inspect())
+	}
+	return result
+}
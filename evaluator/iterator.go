@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"BubblePL/object"
+	"BubblePL/token"
+)
+
+// 脚本中的集合通过哈希表的方法实现迭代协议：
+// iter() 返回要遍历的值，可以是数组、字符串、range等可以遍历的值，也可以是迭代器；
+// 迭代器是有 next() 方法的哈希表，每次调用返回下一个元素，返回null时遍历结束。
+// for (k, v in c) 遍历迭代器时k是元素的序号
+
+// iteratorOf 返回遍历obj的迭代器，不能遍历时返回错误
+func iteratorOf(obj object.Object, pos token.Position) (object.Iterator, object.Object) {
+	if hash, ok := obj.(*object.Hash); ok {
+		if iter, ok := hashMethod(hash, "iter"); ok {
+			obj = applyFunction(iter, nil, nil, pos)
+			if isError(obj) {
+				return nil, obj
+			}
+			if _, ok := obj.(object.Iterable); !ok && !isScriptIterator(obj) {
+				return nil, newError("iter must return an iterable value or an iterator with next, got %s", obj.Type())
+			}
+		}
+	}
+	if hash, ok := obj.(*object.Hash); ok {
+		if next, ok := hashMethod(hash, "next"); ok {
+			return &scriptIterator{next: next, pos: pos}, nil
+		}
+	}
+	if collection, ok := obj.(object.Iterable); ok {
+		return collection.Iterator(), nil
+	}
+	return nil, newError("cannot iterate over %s", obj.Type())
+}
+
+// hashMethod 返回哈希表中名字为name的函数字段，self绑定到这个哈希表
+func hashMethod(hash *object.Hash, name string) (object.Object, bool) {
+	pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]
+	if !ok {
+		return nil, false
+	}
+	switch pair.Value.(type) {
+	case *object.Function, *object.Builtin:
+		return bindSelf(pair.Value, hash, name), true
+	default:
+		return nil, false
+	}
+}
+
+func isScriptIterator(obj object.Object) bool {
+	hash, ok := obj.(*object.Hash)
+	if !ok {
+		return false
+	}
+	_, ok = hashMethod(hash, "next")
+	return ok
+}
+
+// scriptIterator 调用脚本中定义的next方法的迭代器，next出错时停止遍历并记录错误
+type scriptIterator struct {
+	next  object.Object
+	pos   token.Position
+	index int64
+	err   object.Object
+}
+
+func (it *scriptIterator) Next() (object.Object, object.Object, bool) {
+	value := applyFunction(it.next, nil, nil, it.pos)
+	if isError(value) {
+		it.err = value
+		return nil, nil, false
+	}
+	if value == nil || value == NULL {
+		return nil, nil, false
+	}
+	key := &object.Integer{Value: it.index}
+	it.index++
+	return key, value, true
+}
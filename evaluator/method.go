@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"BubblePL/ast"
+	"BubblePL/object"
+	"strings"
+)
+
+// builtinMethod 核心类型的内置方法，receiver是调用方法的值，call用来调用作为参数传入的函数
+type builtinMethod func(receiver object.Object, args []object.Object, call callFunction) object.Object
+
+// callFunction 调用fn并返回结果，fn中的return已经展开
+type callFunction func(fn object.Object, args ...object.Object) object.Object
+
+var functionTypes = argTypes{object.FUNTION_OBJ, object.BUILTIN_OBJ}
+
+var methods = map[object.ObjectType]map[string]builtinMethod{
+	object.STRING_OBJ: {
+		"len": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("len", args); err != nil {
+				return err
+			}
+			return builtins["len"].Fn(receiver)
+		},
+		"upper": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("upper", args); err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToUpper(receiver.(*object.String).Value)}
+		},
+		"lower": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("lower", args); err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToLower(receiver.(*object.String).Value)}
+		},
+		"trim": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("trim", args); err != nil {
+				return err
+			}
+			return &object.String{Value: strings.TrimSpace(receiver.(*object.String).Value)}
+		},
+		"split": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("split", args, argTypes{object.STRING_OBJ}); err != nil {
+				return err
+			}
+			parts := strings.Split(receiver.(*object.String).Value, args[0].(*object.String).Value)
+			elements := make([]object.Object, len(parts))
+			for i, part := range parts {
+				elements[i] = &object.String{Value: part}
+			}
+			return &object.Array{Elements: elements}
+		},
+		"contains": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("contains", args, argTypes{object.STRING_OBJ}); err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.Contains(receiver.(*object.String).Value, args[0].(*object.String).Value))
+		},
+	},
+	object.ARRAY_OBJ: {
+		"len": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("len", args); err != nil {
+				return err
+			}
+			return builtins["len"].Fn(receiver)
+		},
+		"push": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("push", args, nil); err != nil {
+				return err
+			}
+			return builtins["push"].Fn(receiver, args[0])
+		},
+		"map": func(receiver object.Object, args []object.Object, call callFunction) object.Object {
+			if err := checkArgs("map", args, functionTypes); err != nil {
+				return err
+			}
+			elements := receiver.(*object.Array).Elements
+			result := make([]object.Object, 0, len(elements))
+			for _, element := range elements {
+				value := call(args[0], element)
+				if isError(value) {
+					return value
+				}
+				result = append(result, value)
+			}
+			return &object.Array{Elements: result}
+		},
+		"filter": func(receiver object.Object, args []object.Object, call callFunction) object.Object {
+			if err := checkArgs("filter", args, functionTypes); err != nil {
+				return err
+			}
+			result := []object.Object{}
+			for _, element := range receiver.(*object.Array).Elements {
+				keep := call(args[0], element)
+				if isError(keep) {
+					return keep
+				}
+				if isTruthy(keep) {
+					result = append(result, element)
+				}
+			}
+			return &object.Array{Elements: result}
+		},
+		"reduce": func(receiver object.Object, args []object.Object, call callFunction) object.Object {
+			if err := checkArgs("reduce", args, functionTypes, nil); err != nil {
+				return err
+			}
+			acc := args[1]
+			for _, element := range receiver.(*object.Array).Elements {
+				acc = call(args[0], acc, element)
+				if isError(acc) {
+					return acc
+				}
+			}
+			return acc
+		},
+		"join": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("join", args, argTypes{object.STRING_OBJ}); err != nil {
+				return err
+			}
+			elements := receiver.(*object.Array).Elements
+			parts := make([]string, len(elements))
+			for i, element := range elements {
+				parts[i] = element.Inspect()
+			}
+			return &object.String{Value: strings.Join(parts, args[0].(*object.String).Value)}
+		},
+	},
+	object.HASH_OBJ: {
+		"len": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("len", args); err != nil {
+				return err
+			}
+			return &object.Integer{Value: int64(len(receiver.(*object.Hash).Pairs))}
+		},
+		"keys": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("keys", args); err != nil {
+				return err
+			}
+			pairs := receiver.(*object.Hash).SortedPairs()
+			keys := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				keys[i] = pair.Key
+			}
+			return &object.Array{Elements: keys}
+		},
+		"values": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("values", args); err != nil {
+				return err
+			}
+			pairs := receiver.(*object.Hash).SortedPairs()
+			values := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				values[i] = pair.Value
+			}
+			return &object.Array{Elements: values}
+		},
+		"has": func(receiver object.Object, args []object.Object, _ callFunction) object.Object {
+			if err := checkArgs("has", args, nil); err != nil {
+				return err
+			}
+			key, ok := args[0].(object.Hashable)
+			if !ok {
+				return newError("unusable as hash key: %s", args[0].Type())
+			}
+			_, ok = receiver.(*object.Hash).Pairs[key.HashKey()]
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+}
+
+// evalMethodCall 调用receiver的方法。哈希表中名字为方法名的字段优先，字段中的函数调用时self是这个哈希表；
+// 没有这个字段时使用receiver的类型的内置方法
+func evalMethodCall(node *ast.MethodCallExpression, receiver object.Object, env *object.Environment) object.Object {
+	args, named, err := evalArguments(node.Arguments, env)
+	if err != nil {
+		return err
+	}
+	name := node.Method.Value
+	if hash, ok := receiver.(*object.Hash); ok {
+		if pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]; ok {
+			return applyFunction(bindSelf(pair.Value, hash, name), args, named, node.Pos())
+		}
+	}
+	method, ok := methods[receiver.Type()][name]
+	if !ok {
+		return newError("%s has no method %s", receiver.Type(), name)
+	}
+	if len(named) > 0 {
+		return newError("builtin methods do not accept named arguments")
+	}
+	return method(receiver, args, func(fn object.Object, args ...object.Object) object.Object {
+		return applyFunction(fn, args, nil, node.Pos())
+	})
+}
+
+// bindSelf 返回在self绑定到hash的环境中执行的fn，匿名函数使用字段名作为函数名
+func bindSelf(fn object.Object, hash *object.Hash, name string) object.Object {
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return fn
+	}
+	env := object.NewEnclosedEnvironment(function.Env)
+	env.Set("self", hash)
+	bound := &object.Function{
+		Name:       function.Name,
+		Parameters: function.Parameters,
+		Body:       function.Body,
+		Env:        env,
+	}
+	if bound.Name == "" {
+		bound.Name = name
+	}
+	return bound
+}
@@ -0,0 +1,224 @@
+package evaluator
+
+import (
+	"BubblePL/ast"
+	"BubblePL/object"
+	"BubblePL/token"
+	"fmt"
+)
+
+// evalMatchExpression 按顺序尝试每个分支，使用第一个模式匹配并且guard为真的分支。
+// 分支中绑定的变量只在这个分支中可见，没有分支匹配时报错
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isInterrupted(subject) {
+		return subject
+	}
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		mismatch, err := matchPattern(arm.Pattern, subject, armEnv, armEnv.Set)
+		if err != nil {
+			return err
+		}
+		if mismatch != nil {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isInterrupted(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+	err := newError("no match arm matched %s", describeValue(subject))
+	err.Pos = node.Pos()
+	return err
+}
+
+// evalDestructuringLet 解构的 let [a, b] = value，形状不匹配时报错，错误的位置是不匹配的那部分模式
+func evalDestructuringLet(node *ast.LetStatement, env *object.Environment) object.Object {
+	for _, name := range ast.PatternNames(node.Pattern) {
+		if env.IsConst(name.Value) {
+			err := newError("cannot redeclare constant %s", name.Value)
+			err.Pos = name.Pos()
+			return err
+		}
+	}
+	value := Eval(node.Value, env)
+	if isInterrupted(value) {
+		return value
+	}
+	// 先绑定到临时的环境中，整个模式匹配之后才写入env，避免失败时留下一部分变量
+	scratch := object.NewEnclosedEnvironment(env)
+	mismatch, err := matchPattern(node.Pattern, value, scratch, scratch.Set)
+	if err != nil {
+		return err
+	}
+	if mismatch != nil {
+		mismatch.Message = "cannot destructure: " + mismatch.Message
+		return mismatch
+	}
+	for _, name := range ast.PatternNames(node.Pattern) {
+		bound, _ := scratch.Get(name.Value)
+		if node.Token.Type == token.CONST {
+			env.SetConst(name.Value, bound)
+		} else {
+			env.Set(name.Value, bound)
+		}
+	}
+	return nil
+}
+
+// matchPattern 检查value是否匹配pattern，匹配时用bind绑定模式中的变量，默认值在env中计算。
+// 不匹配时mismatch说明原因，位置是不匹配的那部分模式；计算模式中的表达式出错时返回err
+func matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment, bind func(string, object.Object) object.Object) (mismatch *object.Error, err object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil, nil
+	case *ast.BindingPattern:
+		bind(pattern.Name.Value, value)
+		return nil, nil
+	case *ast.LiteralPattern:
+		literal := Eval(pattern.Value, env)
+		if isInterrupted(literal) {
+			return nil, literal
+		}
+		if !literalEqual(literal, value) {
+			return patternMismatch(pattern, "expected %s, got %s", describeValue(literal), describeValue(value)), nil
+		}
+		return nil, nil
+	case *ast.ArrayPattern:
+		return matchArrayPattern(pattern, value, env, bind)
+	case *ast.HashPattern:
+		return matchHashPattern(pattern, value, env, bind)
+	default:
+		return nil, newError("unknown pattern: %T", pattern)
+	}
+}
+
+func matchArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment, bind func(string, object.Object) object.Object) (*object.Error, object.Object) {
+	arr, ok := value.(*object.Array)
+	if !ok {
+		return patternMismatch(pattern, "expected ARRAY, got %s", value.Type()), nil
+	}
+	// 最后一个没有默认值的元素之后的元素都可以缺少
+	n := len(pattern.Elements)
+	required := n
+	for required > 0 && hasDefault(pattern.Elements[required-1]) {
+		required--
+	}
+	length := len(arr.Elements)
+	switch {
+	case pattern.Rest == nil && required < n && (length < required || length > n):
+		return patternMismatch(pattern, "expected an array of %d to %d elements, got %d", required, n, length), nil
+	case pattern.Rest == nil && length != n && required == n:
+		return patternMismatch(pattern, "expected an array of %d %s, got %d", n, pluralElements(n), length), nil
+	case length < required:
+		return patternMismatch(pattern, "expected an array of at least %d %s, got %d", required, pluralElements(required), length), nil
+	}
+	for i, element := range pattern.Elements {
+		if i >= length {
+			if err := bindDefault(element.(*ast.BindingPattern), env, bind); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		mismatch, err := matchPattern(element, arr.Elements[i], env, bind)
+		if err != nil || mismatch != nil {
+			return mismatch, err
+		}
+	}
+	if pattern.Rest != nil {
+		rest := []object.Object{}
+		if length > n {
+			rest = append(rest, arr.Elements[n:]...)
+		}
+		bind(pattern.Rest.Value, &object.Array{Elements: rest})
+	}
+	return nil, nil
+}
+
+func matchHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment, bind func(string, object.Object) object.Object) (*object.Error, object.Object) {
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return patternMismatch(pattern, "expected HASH, got %s", value.Type()), nil
+	}
+	for i, keyNode := range pattern.Keys {
+		key := Eval(keyNode, env)
+		if isInterrupted(key) {
+			return nil, key
+		}
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return nil, newError("unusable as hash key: %s", key.Type())
+		}
+		pair, ok := hash.Pairs[hashable.HashKey()]
+		if !ok {
+			if !hasDefault(pattern.Values[i]) {
+				return patternMismatch(pattern.Values[i], "missing key %s", describeValue(key)), nil
+			}
+			if err := bindDefault(pattern.Values[i].(*ast.BindingPattern), env, bind); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		mismatch, err := matchPattern(pattern.Values[i], pair.Value, env, bind)
+		if err != nil || mismatch != nil {
+			return mismatch, err
+		}
+	}
+	return nil, nil
+}
+
+func hasDefault(pattern ast.Pattern) bool {
+	binding, ok := pattern.(*ast.BindingPattern)
+	return ok && binding.Default != nil
+}
+
+// bindDefault 元素或者键不存在时计算默认值并绑定，默认值可以使用前面绑定的变量
+func bindDefault(pattern *ast.BindingPattern, env *object.Environment, bind func(string, object.Object) object.Object) object.Object {
+	value := Eval(pattern.Default, env)
+	if isInterrupted(value) {
+		return value
+	}
+	bind(pattern.Name.Value, value)
+	return nil
+}
+
+func patternMismatch(pattern ast.Pattern, format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Pos = pattern.Pos()
+	return err
+}
+
+// literalEqual 字面量模式和值是否相等，数字按大小比较，其它值必须类型和值都相同
+func literalEqual(literal, value object.Object) bool {
+	if isNumber(literal) && isNumber(value) {
+		return evalInfixExpression("==", literal, value, false) == TRUE
+	}
+	a, ok := literal.(object.Hashable)
+	if !ok {
+		return false
+	}
+	b, ok := value.(object.Hashable)
+	return ok && a.HashKey() == b.HashKey()
+}
+
+// describeValue 错误信息中的值，字符串带上引号
+func describeValue(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return fmt.Sprintf("%q", str.Value)
+	}
+	return obj.Inspect()
+}
+
+func pluralElements(n int) string {
+	if n == 1 {
+		return "element"
+	}
+	return "elements"
+}
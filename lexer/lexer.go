@@ -2,48 +2,218 @@ package lexer
 
 import (
 	"BubblePL/token"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// 词法错误的代码
+const (
+	CodeIllegalCharacter    = "L0001" // 不能识别的字符
+	CodeUnterminatedComment = "L0002" // 块注释没有结束
+	CodeUnterminatedString  = "L0003" // 字符串没有结束
+	CodeInvalidEscape       = "L0004" // 无效的转义序列
+	CodeInvalidEncoding     = "L0005" // 不是合法的UTF-8编码
+	CodeMalformedNumber     = "L0006" // 数字字面量格式错误
+)
+
+// Error 词法分析中发现的错误，出错的位置会生成一个ILLEGAL Token
+type Error struct {
+	Code    string
+	Message string
+	Pos     token.Position
+	End     token.Position
+}
+
 // Lexer 负责将源代码转换成Tokens
 type Lexer struct {
-	input        string // 程序字符串
-	position     int    // 当前处理的字符在字符串中的位置
-	readPosition int    // 下一个要读取的字符串的位置 position + 1
-	ch           byte   // 当前处理的字符串
+	filename     string  // 文件名，用于位置信息
+	input        string  // 程序字符串
+	position     int     // 当前处理的字符在字符串中的字节位置
+	readPosition int     // 下一个要读取的字符的字节位置 position + 当前字符的UTF-8编码长度
+	ch           rune    // 当前处理的字符
+	line         int     // 当前处理的字符所在的行，从1开始
+	column       int     // 当前处理的字符所在的列，按字符计数，从1开始
+	keepComments bool    // 是否把注释记录到Token.Leading中
+	errors       []Error // 词法错误
+	interps      []int   // 正在读取的插值表达式，每一项是插值表达式中未闭合的 { 的数量
+}
+
+// KeepComments 设置是否保留注释，保留的注释记录在紧跟着注释的Token的Leading中
+func (l *Lexer) KeepComments(keep bool) {
+	l.keepComments = keep
+}
+
+// Errors 返回目前为止发现的词法错误
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+// ErrorFor 返回ILLEGAL Token对应的词法错误，也就是Token范围内的第一个错误
+func (l *Lexer) ErrorFor(tk token.Token) (Error, bool) {
+	for _, err := range l.errors {
+		if err.Pos.Offset == tk.Pos.Offset || tk.Pos.Offset < err.Pos.Offset && err.Pos.Offset < tk.End.Offset {
+			return err, true
+		}
+	}
+	return Error{}, false
+}
+
+// addError 记录一个从start开始到当前字符之前结束的词法错误
+func (l *Lexer) addError(code string, start token.Position, format string, a ...interface{}) {
+	l.errors = append(l.errors, Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+		Pos:     start,
+		End:     l.pos(),
+	})
 }
 
 // readChar 读取下一个字符
 func (l *Lexer) readChar() {
+	// 已经越过字符串结尾时位置不再移动
+	if l.readPosition > len(l.input) {
+		l.ch = 0
+		return
+	}
+	// 根据离开的字符更新行列号
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
 	// 如果读取完成，就把字符串赋值为\0代表字符串结束
+	width := 1
 	if l.readPosition >= len(l.input) {
 		// ASCII 0 = NUL
 		l.ch = 0
 	} else {
-		// 处理下一个字符
-		l.ch = l.input[l.readPosition]
+		// 解码下一个字符，非法的UTF-8编码得到utf8.RuneError，宽度为1
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	// 更新位置信息
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
+}
+
+// pos 返回当前处理的字符的位置
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
 }
 
 // NextToken 生成源代码的下一个Token
 func (l *Lexer) NextToken() token.Token {
-	l.eatWhitespace()
+	comments, ok := l.skipTrivia()
+	var tk token.Token
+	if !ok {
+		// 没有结束的块注释生成一个ILLEGAL Token
+		last := comments[len(comments)-1]
+		tk = token.Token{Type: token.ILLEGAL, Literal: last.Text, Pos: last.Pos, End: last.End}
+		comments = comments[:len(comments)-1]
+	} else {
+		start := l.pos()
+		tk = l.nextToken()
+		tk.Pos = start
+		if tk.Type == token.EOF {
+			tk.End = start
+		} else {
+			tk.End = l.pos()
+		}
+	}
+	if l.keepComments && len(comments) > 0 {
+		tk.Leading = comments
+	}
+	return tk
+}
+
+// skipTrivia 跳过空白和注释，返回跳过的注释。遇到没有结束的块注释时返回false，
+// 这时最后一条注释就是没有结束的块注释
+func (l *Lexer) skipTrivia() ([]token.Comment, bool) {
+	var comments []token.Comment
+	for {
+		l.eatWhitespace()
+		start := l.pos()
+		terminated := true
+		switch {
+		case l.ch == '#' || l.ch == '/' && l.peekChar() == '/':
+			l.skipLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			terminated = l.skipBlockComment()
+		default:
+			return comments, true
+		}
+		comments = append(comments, token.Comment{
+			Text: l.input[start.Offset:l.position],
+			Pos:  start,
+			End:  l.pos(),
+		})
+		if !terminated {
+			l.addError(CodeUnterminatedComment, start, "unterminated block comment")
+			return comments, false
+		}
+	}
+}
+
+// skipLineComment 跳过 // 或者 # 开始的注释，停在换行符上
+func (l *Lexer) skipLineComment() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// skipBlockComment 跳过 /* */ 注释，块注释可以嵌套。注释没有结束时返回false
+func (l *Lexer) skipBlockComment() bool {
+	depth := 0
+	for l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return true
+			}
+		}
+		l.readChar()
+	}
+	return false
+}
+
+// nextToken 读取下一个Token的类别和字面量，读取完成后当前字符位于Token之后的第一个字符
+func (l *Lexer) nextToken() token.Token {
 	var tk token.Token
 	switch l.ch {
 	case '+':
-		tk = token.New(token.PLUS, l.ch)
-	case '=':
 		if l.peekChar() == '=' {
-			tk = token.Token{Type: token.EQ, Literal: string(l.ch) + string(l.peekChar())}
+			tk = token.Token{Type: token.PLUS_ASSIGN, Literal: "+="}
 			l.readChar()
 		} else {
+			tk = token.New(token.PLUS, l.ch)
+		}
+	case '=':
+		switch l.peekChar() {
+		case '=':
+			tk = token.Token{Type: token.EQ, Literal: "=="}
+			l.readChar()
+		case '>':
+			tk = token.Token{Type: token.FAT_ARROW, Literal: "=>"}
+			l.readChar()
+		default:
 			tk = token.New(token.ASSIGN, l.ch)
 		}
 	case '!':
 		if l.peekChar() == '=' {
-			tk = token.Token{Type: token.NOT_EQ, Literal: string(l.ch) + string(l.peekChar())}
+			tk = token.Token{Type: token.NOT_EQ, Literal: "!="}
 			l.readChar()
 		} else {
 			tk = token.New(token.BAND, l.ch)
@@ -53,35 +223,132 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tk = token.New(token.COMMA, l.ch)
 	case '{':
+		if top := len(l.interps) - 1; top >= 0 {
+			l.interps[top]++
+		}
 		tk = token.New(token.LBRACE, l.ch)
 	case '}':
+		if top := len(l.interps) - 1; top >= 0 {
+			if l.interps[top] == 0 {
+				// 插值表达式结束，继续读取字符串剩下的部分
+				l.interps = l.interps[:top]
+				return l.readStringToken(false)
+			}
+			l.interps[top]--
+		}
 		tk = token.New(token.RBRACE, l.ch)
 	case '(':
 		tk = token.New(token.LPAREN, l.ch)
 	case ')':
 		tk = token.New(token.RPAREN, l.ch)
 	case '/':
-		tk = token.New(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			tk = token.Token{Type: token.SLASH_ASSIGN, Literal: "/="}
+			l.readChar()
+		} else {
+			tk = token.New(token.SLASH, l.ch)
+		}
 	case '*':
-		tk = token.New(token.ASTERISK, l.ch)
+		switch l.peekChar() {
+		case '*':
+			tk = token.Token{Type: token.POWER, Literal: "**"}
+			l.readChar()
+		case '=':
+			tk = token.Token{Type: token.ASTERISK_ASSIGN, Literal: "*="}
+			l.readChar()
+		default:
+			tk = token.New(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tk = token.New(token.PERCENT, l.ch)
 	case '-':
-		tk = token.New(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tk = token.Token{Type: token.MINUS_ASSIGN, Literal: "-="}
+			l.readChar()
+		} else {
+			tk = token.New(token.MINUS, l.ch)
+		}
 	case '<':
-		tk = token.New(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tk = token.Token{Type: token.LT_EQ, Literal: "<="}
+			l.readChar()
+		case '<':
+			tk = token.Token{Type: token.SHL, Literal: "<<"}
+			l.readChar()
+		default:
+			tk = token.New(token.LT, l.ch)
+		}
 	case '>':
-		tk = token.New(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tk = token.Token{Type: token.GT_EQ, Literal: ">="}
+			l.readChar()
+		case '>':
+			tk = token.Token{Type: token.SHR, Literal: ">>"}
+			l.readChar()
+		default:
+			tk = token.New(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tk = token.Token{Type: token.AND, Literal: "&&"}
+			l.readChar()
+		} else {
+			tk = token.New(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tk = token.Token{Type: token.OR, Literal: "||"}
+			l.readChar()
+		} else {
+			tk = token.New(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tk = token.New(token.BIT_XOR, l.ch)
+	case '~':
+		tk = token.New(token.BIT_NOT, l.ch)
 	case '[':
 		tk = token.New(token.LBRACKET, l.ch)
 	case ']':
 		tk = token.New(token.RBRACKET, l.ch)
 	case ':':
 		tk = token.New(token.COLON, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			tk = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			l.readChar()
+			l.readChar()
+		} else {
+			tk = token.New(token.DOT, l.ch)
+		}
+	case '?':
+		switch l.peekChar() {
+		case '?':
+			tk = token.Token{Type: token.NULLISH, Literal: "??"}
+			l.readChar()
+		case '.':
+			tk = token.Token{Type: token.OPTIONAL_DOT, Literal: "?."}
+			l.readChar()
+		case '[':
+			tk = token.Token{Type: token.OPTIONAL_LBRACKET, Literal: "?["}
+			l.readChar()
+		default:
+			tk = token.New(token.QUESTION, l.ch)
+		}
 	case 0:
 		tk.Type = token.EOF
 		tk.Literal = ""
 	case '"':
+		return l.readStringToken(true)
+	case '`':
+		start := l.position
+		value, ok := l.readRawString()
+		if !ok {
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start:l.position]}
+		}
 		tk.Type = token.STRING
-		tk.Literal = l.readString()
+		tk.Literal = value
 	default:
 		if isLetter(l.ch) {
 			tk.Literal = l.readIdentifier()
@@ -89,11 +356,15 @@ func (l *Lexer) NextToken() token.Token {
 			// 因为在Lexer.readIdentifier中已经调用Lexer.readChar将position的位置移动到了当前identifier后第一个位置，这里直接返回
 			return tk
 		} else if isNumber(l.ch) {
+			start := l.pos()
 			tk.Literal = l.readNumber()
-			tk.Type = token.INT
+			var msg string
+			if tk.Type, msg = checkNumber(tk.Literal); msg != "" {
+				l.addError(CodeMalformedNumber, start, "%s", msg)
+			}
 			return tk
 		} else {
-			tk = token.New(token.ILLEGAL, l.ch)
+			return l.illegalCharacter()
 		}
 	}
 	// 读取
@@ -101,27 +372,181 @@ func (l *Lexer) NextToken() token.Token {
 	return tk
 }
 
-func (l *Lexer) readString() string {
-	pos := l.position + 1
+// illegalCharacter 把当前字符作为ILLEGAL Token返回并记录错误
+func (l *Lexer) illegalCharacter() token.Token {
+	start := l.pos()
+	tk := token.Token{Type: token.ILLEGAL, Literal: l.input[l.position:l.readPosition]}
+	invalid := l.ch == utf8.RuneError && l.readPosition-l.position == 1
+	l.readChar()
+	if invalid {
+		l.addError(CodeInvalidEncoding, start, "invalid UTF-8 encoding %q", tk.Literal)
+	} else {
+		l.addError(CodeIllegalCharacter, start, "illegal character %q", tk.Literal)
+	}
+	return tk
+}
+
+// readStringToken 读取双引号字符串的一段。begin为true时从开头的双引号开始，否则从插值表达式结尾的 } 开始。
+// 没有插值的字符串生成STRING，插值字符串依次生成INTERP_START、INTERP_MID和INTERP_END
+func (l *Lexer) readStringToken(begin bool) token.Token {
+	start := l.position
+	value, interpolation, ok := l.readString()
+	if l.ch == '"' || l.ch == '{' {
+		l.readChar()
+	}
+	if !ok {
+		return token.Token{Type: token.ILLEGAL, Literal: l.input[start:l.position]}
+	}
+	tk := token.Token{Literal: value}
+	switch {
+	case begin && !interpolation:
+		tk.Type = token.STRING
+	case begin:
+		tk.Type = token.INTERP_START
+	case interpolation:
+		tk.Type = token.INTERP_MID
+	default:
+		tk.Type = token.INTERP_END
+	}
+	return tk
+}
+
+// readString 读取双引号字符串并解码其中的转义序列，读取完成后当前字符是结尾的双引号，
+// 遇到 ${ 时停在 { 上并返回interpolation为true。
+// 双引号字符串不能跨行，字符串没有结束或者包含无效的转义序列时返回false
+func (l *Lexer) readString() (value string, interpolation bool, ok bool) {
+	start := l.pos()
+	var out strings.Builder
+	valid := true
+	l.readChar()
 	for {
+		switch l.ch {
+		case '"':
+			return out.String(), false, valid
+		case '$':
+			if l.peekChar() == '{' {
+				l.readChar()
+				l.interps = append(l.interps, 0)
+				return out.String(), true, valid
+			}
+			out.WriteRune(l.ch)
+			l.readChar()
+		case '\n', 0:
+			l.addError(CodeUnterminatedString, start, "unterminated string literal")
+			return "", false, false
+		case '\\':
+			if !l.readEscape(&out) {
+				valid = false
+			}
+		default:
+			// 原样保留字符的编码，非法的UTF-8字节也不做替换
+			out.WriteString(l.input[l.position:l.readPosition])
+			l.readChar()
+		}
+	}
+}
+
+// readEscape 解码当前位置的转义序列并写入out，读取完成后当前字符位于转义序列之后。
+// 支持 \n \t \r \0 \\ \" \$ 以及 \u{1F600} 形式的Unicode码点
+func (l *Lexer) readEscape(out *strings.Builder) bool {
+	start := l.pos()
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"', '$':
+		out.WriteRune(l.ch)
+	case 'u':
+		return l.readUnicodeEscape(start, out)
+	case '\n', 0:
+		// 交给readString报告字符串没有结束
+		return true
+	default:
+		l.readChar()
+		l.addError(CodeInvalidEscape, start, "invalid escape sequence %q", l.input[start.Offset:l.position])
+		return false
+	}
+	l.readChar()
+	return true
+}
+
+// readUnicodeEscape 解码 \u{...}，花括号中是1到6位十六进制数字
+func (l *Lexer) readUnicodeEscape(start token.Position, out *strings.Builder) bool {
+	l.readChar()
+	valid := l.ch == '{'
+	var r rune
+	digits := 0
+	if valid {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		for isHexDigit(l.ch) {
+			r = r*16 + hexValue(l.ch)
+			digits++
+			l.readChar()
+		}
+		valid = l.ch == '}' && 0 < digits && digits <= 6
+		if valid {
+			l.readChar()
+		}
+	}
+	if !valid {
+		l.addError(CodeInvalidEscape, start, "invalid unicode escape %q, expected the form \\u{1F600}", l.input[start.Offset:l.position])
+		return false
+	}
+	if !utf8.ValidRune(r) {
+		l.addError(CodeInvalidEscape, start, "invalid unicode code point %q", l.input[start.Offset:l.position])
+		return false
+	}
+	out.WriteRune(r)
+	return true
+}
+
+// readRawString 读取反引号字符串，原样保留其中的内容，可以跨行。读取完成后当前字符是结尾的反引号
+func (l *Lexer) readRawString() (string, bool) {
+	start := l.pos()
+	l.readChar()
+	for l.ch != '`' {
+		if l.ch == 0 {
+			l.addError(CodeUnterminatedString, start, "unterminated raw string literal")
+			return "", false
 		}
+		l.readChar()
+	}
+	return l.input[start.Offset+1 : l.position], true
+}
+
+// isHexDigit 检查字符是否为十六进制数字
+func isHexDigit(ch rune) bool {
+	return isNumber(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// hexValue 返回十六进制数字的值
+func hexValue(ch rune) rune {
+	switch {
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	case 'A' <= ch && ch <= 'F':
+		return ch - 'A' + 10
+	default:
+		return ch - '0'
 	}
-	return l.input[pos:l.position]
 }
 
-// isLetter 检测byte是否是字母
-func isLetter(ch byte) bool {
+// isLetter 检测字符是否是字母，包括中文等Unicode字母
+func isLetter(ch rune) bool {
 	if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' {
 		return true
 	}
-	return false
+	return ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-// isNumber 检查byte是否为数字
-func isNumber(ch byte) bool {
+// isNumber 检查字符是否为数字
+func isNumber(ch rune) bool {
 	if '0' <= ch && ch <= '9' {
 		return true
 	}
@@ -137,13 +562,100 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-// readNumber 读取数字的字面量
+// readNumber 读取数字的字面量。为了给出准确的错误信息，紧跟在数字后面的字母、数字和 _ 都属于这个字面量，
+// 十进制数中小数点后面是数字时读取小数部分，e 后面可以有正负号
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for isNumber(l.ch) {
-		l.readChar()
+	decimal := !(l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()))
+	for {
+		switch {
+		case isLetter(l.ch) || isNumber(l.ch):
+			exponent := decimal && (l.ch == 'e' || l.ch == 'E')
+			l.readChar()
+			if exponent && (l.ch == '+' || l.ch == '-') {
+				l.readChar()
+			}
+		case decimal && l.ch == '.' && isNumber(l.peekChar()):
+			l.readChar()
+		default:
+			return l.input[position:l.position]
+		}
+	}
+}
+
+// checkNumber 检查数字字面量，返回INT或者FLOAT。支持 0x、0o、0b 前缀和分隔数字的 _，
+// 十进制整数不能以0开头，字面量不合法时返回错误信息
+func checkNumber(lit string) (token.TokenType, string) {
+	base, name, digits := 10, "decimal", lit
+	if len(lit) >= 2 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base, name = 16, "hexadecimal"
+		case 'o', 'O':
+			base, name = 8, "octal"
+		case 'b', 'B':
+			base, name = 2, "binary"
+		}
+		if base != 10 {
+			digits = lit[2:]
+		}
+	}
+
+	tokenType := token.TokenType(token.INT)
+	hasDigits, fraction, exponent := false, false, false
+	for i := 0; i < len(digits); i++ {
+		ch := digits[i]
+		switch {
+		case isDigit(ch, base):
+			hasDigits = true
+		case ch == '_':
+			// _ 只能出现在两个数字之间，或者前缀和数字之间
+			prevOk := i == 0 && base != 10 || i > 0 && isDigit(digits[i-1], base)
+			if !prevOk || i+1 >= len(digits) || !isDigit(digits[i+1], base) {
+				return token.ILLEGAL, "'_' must separate successive digits"
+			}
+		case base == 10 && ch == '.' && !fraction && !exponent:
+			fraction = true
+			tokenType = token.FLOAT
+		case base == 10 && (ch == 'e' || ch == 'E') && !exponent:
+			exponent = true
+			tokenType = token.FLOAT
+			if i+1 < len(digits) && (digits[i+1] == '+' || digits[i+1] == '-') {
+				i++
+			}
+			if i+1 >= len(digits) || !isNumber(rune(digits[i+1])) {
+				return token.ILLEGAL, "exponent has no digits"
+			}
+		default:
+			r, _ := utf8.DecodeRuneInString(digits[i:])
+			if r == '.' {
+				return token.ILLEGAL, fmt.Sprintf("invalid character %q in %s literal", r, name)
+			}
+			return token.ILLEGAL, fmt.Sprintf("invalid digit %q in %s literal", r, name)
+		}
+	}
+	if !hasDigits {
+		return token.ILLEGAL, fmt.Sprintf("%s literal has no digits", name)
+	}
+	// 010 在其它语言中可能是八进制，为了避免歧义不允许十进制整数以0开头
+	if base == 10 && tokenType == token.INT && len(lit) > 1 && lit[0] == '0' {
+		return token.ILLEGAL, "leading zeros are not allowed in decimal integer literals, use 0o for octal"
+	}
+	return tokenType, ""
+}
+
+// isDigit 检查字符是否是base进制的数字
+func isDigit(ch byte, base int) bool {
+	switch base {
+	case 2:
+		return ch == '0' || ch == '1'
+	case 8:
+		return '0' <= ch && ch <= '7'
+	case 16:
+		return isHexDigit(rune(ch))
+	default:
+		return isNumber(rune(ch))
 	}
-	return l.input[position:l.position]
 }
 
 // eatWhitespace 去掉无意义的符号
@@ -154,21 +666,35 @@ func (l *Lexer) eatWhitespace() {
 }
 
 // peekChar获取readPosition的字符
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
 func New(input string) *Lexer {
+	return NewWithFilename("", input)
+}
+
+// NewWithFilename 创建Lexer，生成的Token的位置信息中会带上文件名
+func NewWithFilename(filename, input string) *Lexer {
 	l := &Lexer{
+		filename:     filename,
 		input:        input,
 		position:     0,
 		readPosition: 0,
 		ch:           0,
+		line:         1,
+		column:       0,
 	}
 	// 初始化position=0, readPosition=1, ch=input的第一个字符
 	l.readChar()
+	// 跳过文件开头的BOM
+	if l.ch == '\uFEFF' {
+		l.readChar()
+		l.column = 1
+	}
 	return l
 }
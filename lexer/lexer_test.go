@@ -13,7 +13,7 @@ let add = fn(x, y) {
 	x + y;
 };
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5
 if (5 < 10) {
 	return true;
@@ -74,7 +74,7 @@ if (5 < 10) {
 		{token.IDENT, "ten"},
 		{token.RPAREN, ")"},
 		{token.SEMICOLON, ";"},
-		// !-/*5;
+		// !-/ *5;
 		{token.BAND, "!"},
 		{token.MINUS, "-"},
 		{token.SLASH, "/"},
@@ -147,3 +147,342 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := "let x = 5;\n  add(x, \"hi\")"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+		expectedEnd     token.Position
+	}{
+		{"let", token.Position{Filename: "test.bpl", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "test.bpl", Offset: 3, Line: 1, Column: 4}},
+		{"x", token.Position{Filename: "test.bpl", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "test.bpl", Offset: 5, Line: 1, Column: 6}},
+		{"=", token.Position{Filename: "test.bpl", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "test.bpl", Offset: 7, Line: 1, Column: 8}},
+		{"5", token.Position{Filename: "test.bpl", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "test.bpl", Offset: 9, Line: 1, Column: 10}},
+		{";", token.Position{Filename: "test.bpl", Offset: 9, Line: 1, Column: 10}, token.Position{Filename: "test.bpl", Offset: 10, Line: 1, Column: 11}},
+		{"add", token.Position{Filename: "test.bpl", Offset: 13, Line: 2, Column: 3}, token.Position{Filename: "test.bpl", Offset: 16, Line: 2, Column: 6}},
+		{"(", token.Position{Filename: "test.bpl", Offset: 16, Line: 2, Column: 6}, token.Position{Filename: "test.bpl", Offset: 17, Line: 2, Column: 7}},
+		{"x", token.Position{Filename: "test.bpl", Offset: 17, Line: 2, Column: 7}, token.Position{Filename: "test.bpl", Offset: 18, Line: 2, Column: 8}},
+		{",", token.Position{Filename: "test.bpl", Offset: 18, Line: 2, Column: 8}, token.Position{Filename: "test.bpl", Offset: 19, Line: 2, Column: 9}},
+		{"hi", token.Position{Filename: "test.bpl", Offset: 20, Line: 2, Column: 10}, token.Position{Filename: "test.bpl", Offset: 24, Line: 2, Column: 14}},
+		{")", token.Position{Filename: "test.bpl", Offset: 24, Line: 2, Column: 14}, token.Position{Filename: "test.bpl", Offset: 25, Line: 2, Column: 15}},
+		{"", token.Position{Filename: "test.bpl", Offset: 25, Line: 2, Column: 15}, token.Position{Filename: "test.bpl", Offset: 25, Line: 2, Column: 15}},
+		{"", token.Position{Filename: "test.bpl", Offset: 25, Line: 2, Column: 15}, token.Position{Filename: "test.bpl", Offset: 25, Line: 2, Column: 15}},
+	}
+	l := NewWithFilename("test.bpl", input)
+
+	for idx, test := range tests {
+		tk := l.NextToken()
+		if tk.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal error. expected=%q, but got=%q",
+				idx, test.expectedLiteral, tk.Literal)
+		}
+		if tk.Pos != test.expectedPos {
+			t.Fatalf("tests[%d] - Pos error. expected=%+v, but got=%+v",
+				idx, test.expectedPos, tk.Pos)
+		}
+		if tk.End != test.expectedEnd {
+			t.Fatalf("tests[%d] - End error. expected=%+v, but got=%+v",
+				idx, test.expectedEnd, tk.End)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `#!/usr/bin/env bubble
+let x = 5; // five
+/* outer /* nested */ still comment */ x
+# last`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLeading []string
+	}{
+		{token.LET, "let", []string{"#!/usr/bin/env bubble"}},
+		{token.IDENT, "x", nil},
+		{token.ASSIGN, "=", nil},
+		{token.INT, "5", nil},
+		{token.SEMICOLON, ";", nil},
+		{token.IDENT, "x", []string{"// five", "/* outer /* nested */ still comment */"}},
+		{token.EOF, "", []string{"# last"}},
+	}
+	l := New(input)
+	l.KeepComments(true)
+
+	for idx, test := range tests {
+		tk := l.NextToken()
+		if tk.Type != test.expectedType || tk.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				idx, test.expectedType, test.expectedLiteral, tk.Type, tk.Literal)
+		}
+		if len(tk.Leading) != len(test.expectedLeading) {
+			t.Fatalf("tests[%d] - wrong number of comments. expected=%d, got=%d",
+				idx, len(test.expectedLeading), len(tk.Leading))
+		}
+		for i, comment := range tk.Leading {
+			if comment.Text != test.expectedLeading[i] {
+				t.Errorf("tests[%d] - comment[%d] expected=%q, got=%q",
+					idx, i, test.expectedLeading[i], comment.Text)
+			}
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedCode    string
+		expectedPos     string
+	}{
+		{"let x = @;", "@", CodeIllegalCharacter, "1:9"},
+		{"x /* a /* b */", "/* a /* b */", CodeUnterminatedComment, "1:3"},
+		{"let s = \"abc\nlet t = 1;", "\"abc", CodeUnterminatedString, "1:9"},
+		{"let s = `abc", "`abc", CodeUnterminatedString, "1:9"},
+		{`"a\qb";`, `"a\qb"`, CodeInvalidEscape, "1:3"},
+		{`"\u{}"`, `"\u{}"`, CodeInvalidEscape, "1:2"},
+		{`"\u1F600"`, `"\u1F600"`, CodeInvalidEscape, "1:2"},
+		{`"\u{D800}"`, `"\u{D800}"`, CodeInvalidEscape, "1:2"},
+		{"名字 = 😀;", "😀", CodeIllegalCharacter, "1:6"},
+		{"x = \xff;", "\xff", CodeInvalidEncoding, "1:5"},
+		{"x = 0x;", "0x", CodeMalformedNumber, "1:5"},
+		{"x = 0xFG;", "0xFG", CodeMalformedNumber, "1:5"},
+		{"x = 0b102;", "0b102", CodeMalformedNumber, "1:5"},
+		{"x = 0o8;", "0o8", CodeMalformedNumber, "1:5"},
+		{"x = 1__000;", "1__000", CodeMalformedNumber, "1:5"},
+		{"x = 1000_;", "1000_", CodeMalformedNumber, "1:5"},
+		{"x = 1_.5;", "1_.5", CodeMalformedNumber, "1:5"},
+		{"x = 1e;", "1e", CodeMalformedNumber, "1:5"},
+		{"x = 1e+;", "1e+", CodeMalformedNumber, "1:5"},
+		{"x = 12abc;", "12abc", CodeMalformedNumber, "1:5"},
+		{"x = 1.5.5;", "1.5.5", CodeMalformedNumber, "1:5"},
+		{"x = 010;", "010", CodeMalformedNumber, "1:5"},
+		{"x = 089;", "089", CodeMalformedNumber, "1:5"},
+		{"x = 0_10;", "0_10", CodeMalformedNumber, "1:5"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		var illegal token.Token
+		for tk := l.NextToken(); tk.Type != token.EOF; tk = l.NextToken() {
+			if tk.Type == token.ILLEGAL {
+				illegal = tk
+			}
+		}
+		if illegal.Literal != tt.expectedLiteral {
+			t.Errorf("%q: wrong ILLEGAL literal. expected=%q, got=%q", tt.input, tt.expectedLiteral, illegal.Literal)
+		}
+		if len(l.Errors()) != 1 {
+			t.Errorf("%q: expected 1 error, got=%d", tt.input, len(l.Errors()))
+			continue
+		}
+		err := l.Errors()[0]
+		if err.Code != tt.expectedCode {
+			t.Errorf("%q: wrong code. expected=%s, got=%s", tt.input, tt.expectedCode, err.Code)
+		}
+		if err.Pos.String() != tt.expectedPos {
+			t.Errorf("%q: wrong position. expected=%s, got=%s", tt.input, tt.expectedPos, err.Pos)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world"`, "hello world"},
+		{`"a\nb\tc\rd"`, "a\nb\tc\rd"},
+		{`"say \"hi\" \\ bye"`, `say "hi" \ bye`},
+		{`"nul\0"`, "nul\x00"},
+		{`"\u{41}\u{1F600}"`, "A\U0001F600"},
+		{"`raw \\n ${x}`", `raw \n ${x}`},
+		{"`line one\nline \"two\"`", "line one\nline \"two\""},
+		{`""`, ""},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tk := l.NextToken()
+		if tk.Type != token.STRING {
+			t.Errorf("%s: expected STRING, got=%s %q", tt.input, tk.Type, tk.Literal)
+			continue
+		}
+		if tk.Literal != tt.expected {
+			t.Errorf("%s: wrong value. expected=%q, got=%q", tt.input, tt.expected, tk.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%s: expected EOF, got=%s %q", tt.input, next.Type, next.Literal)
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	input := `"a ${x + "${y}"} b ${ {1: 2}[1] } c" "\${x}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTERP_START, "a "},
+		{token.IDENT, "x"},
+		{token.PLUS, "+"},
+		{token.INTERP_START, ""},
+		{token.IDENT, "y"},
+		{token.INTERP_END, ""},
+		{token.INTERP_MID, " b "},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.INT, "2"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.RBRACKET, "]"},
+		{token.INTERP_END, " c"},
+		{token.STRING, "${x}"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for idx, test := range tests {
+		tk := l.NextToken()
+		if tk.Type != test.expectedType || tk.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				idx, test.expectedType, test.expectedLiteral, tk.Type, tk.Literal)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let 名字 = \"你好, 😀\";\nnaïve + 名字"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     string
+	}{
+		{token.LET, "let", "1:1"},
+		{token.IDENT, "名字", "1:5"},
+		{token.ASSIGN, "=", "1:8"},
+		{token.STRING, "你好, 😀", "1:10"},
+		{token.SEMICOLON, ";", "1:17"},
+		{token.IDENT, "naïve", "2:1"},
+		{token.PLUS, "+", "2:7"},
+		{token.IDENT, "名字", "2:9"},
+		{token.EOF, "", "2:11"},
+	}
+	l := New(input)
+
+	for idx, test := range tests {
+		tk := l.NextToken()
+		if tk.Type != test.expectedType || tk.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				idx, test.expectedType, test.expectedLiteral, tk.Type, tk.Literal)
+		}
+		if tk.Pos.String() != test.expectedPos {
+			t.Fatalf("tests[%d] - wrong position. expected=%s, got=%s", idx, test.expectedPos, tk.Pos)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `5 0 0e1 3.14 0.5 1e-9 2E+10 6e3 1.5e2 1. x.5 0xFF 0Xdead_beef 0o755 0b1010_0101 1_000_000 1_000.000_1 1e1_0`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.INT, "0"},
+		{token.FLOAT, "0e1"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "0.5"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2E+10"},
+		{token.FLOAT, "6e3"},
+		{token.FLOAT, "1.5e2"},
+		// 小数点后面没有数字时不属于这个数字
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.INT, "5"},
+		{token.INT, "0xFF"},
+		{token.INT, "0Xdead_beef"},
+		{token.INT, "0o755"},
+		{token.INT, "0b1010_0101"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "1_000.000_1"},
+		{token.FLOAT, "1e1_0"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for idx, test := range tests {
+		tk := l.NextToken()
+		if tk.Type != test.expectedType || tk.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				idx, test.expectedType, test.expectedLiteral, tk.Type, tk.Literal)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `<= >= < > % ** * && & || | ^ ~ << >> != ... += -= *= /= const . while for break continue in => match ? ?? ?. ?[`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.ASTERISK, "*"},
+		{token.AND, "&&"},
+		{token.BIT_AND, "&"},
+		{token.OR, "||"},
+		{token.BIT_OR, "|"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.SHL, "<<"},
+		{token.SHR, ">>"},
+		{token.NOT_EQ, "!="},
+		{token.ELLIPSIS, "..."},
+		{token.PLUS_ASSIGN, "+="},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.CONST, "const"},
+		{token.DOT, "."},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IN, "in"},
+		{token.FAT_ARROW, "=>"},
+		{token.MATCH, "match"},
+		{token.QUESTION, "?"},
+		{token.NULLISH, "??"},
+		{token.OPTIONAL_DOT, "?."},
+		{token.OPTIONAL_LBRACKET, "?["},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for idx, test := range tests {
+		tk := l.NextToken()
+		if tk.Type != test.expectedType || tk.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				idx, test.expectedType, test.expectedLiteral, tk.Type, tk.Literal)
+		}
+	}
+}
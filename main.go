@@ -2,9 +2,124 @@ package main
 
 import (
 	"BubblePL/repl"
+	"flag"
+	"fmt"
+	"io"
 	"os"
 )
 
+const usage = `Usage:
+  bubble [--checked] <command>   --checked reports integer overflow instead of switching to big integers
+
+  bubble                         start the REPL, or run the program read from stdin when it is not a terminal
+  bubble repl                    start the REPL
+  bubble run <file> [args...]    run a script file, "-" reads the script from stdin
+  bubble <file> [args...]        same as run, used by "#!/usr/bin/env bubble" scripts
+  bubble eval -e <code> [args...]
+  bubble -e <code> [args...]     evaluate code and print the result
+`
+
+// 退出码
+const (
+	exitOK    = 0 // 执行成功
+	exitError = 1 // 解析或者执行出错
+	exitUsage = 2 // 命令行参数错误
+)
+
 func main() {
-	repl.Start(os.Stdin, os.Stdout)
+	c := &command{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// command 一次命令行调用，保存输入输出和全局选项
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	checked        bool // --checked，整数运算溢出时报错
+}
+
+// run 根据命令行参数执行对应的子命令，返回退出码
+func (c *command) run(arguments []string) int {
+	for len(arguments) > 0 && (arguments[0] == "--checked" || arguments[0] == "-checked") {
+		c.checked = true
+		arguments = arguments[1:]
+	}
+	if len(arguments) == 0 {
+		if isTerminal(c.stdin) {
+			repl.Start(c.stdin, c.stdout, c.checked)
+			return exitOK
+		}
+		return c.runFile("-", nil)
+	}
+
+	switch arguments[0] {
+	case "repl":
+		repl.Start(c.stdin, c.stdout, c.checked)
+		return exitOK
+	case "run":
+		if len(arguments) < 2 {
+			fmt.Fprint(c.stderr, "bubble run: missing script file\n\n"+usage)
+			return exitUsage
+		}
+		return c.runFile(arguments[1], arguments[2:])
+	case "eval", "-e":
+		return c.eval(arguments)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(c.stdout, usage)
+		return exitOK
+	default:
+		return c.runFile(arguments[0], arguments[1:])
+	}
+}
+
+// eval 执行 eval -e <code> 或者 -e <code>
+func (c *command) eval(arguments []string) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprint(c.stderr, usage)
+	}
+	code := flags.String("e", "", "code to evaluate")
+	// -e <code> 的形式直接把 -e 交给flag解析
+	if arguments[0] == "eval" {
+		arguments = arguments[1:]
+	}
+	if err := flags.Parse(arguments); err != nil {
+		return exitUsage
+	}
+	if *code == "" {
+		fmt.Fprint(c.stderr, "bubble eval: missing -e <code>\n\n"+usage)
+		return exitUsage
+	}
+	return runSource("<eval>", *code, flags.Args(), c.stdout, c.stderr, true, c.checked)
+}
+
+// runFile 执行脚本文件，filename为 - 时从标准输入读取脚本
+func (c *command) runFile(filename string, args []string) int {
+	var source []byte
+	var err error
+	if filename == "-" {
+		filename = "<stdin>"
+		source, err = io.ReadAll(c.stdin)
+	} else {
+		source, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "bubble: %s\n", err)
+		return exitError
+	}
+	return runSource(filename, string(source), args, c.stdout, c.stderr, false, c.checked)
+}
+
+// isTerminal 判断输入是否是终端，而不是管道或者文件
+func isTerminal(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript := func(name, source string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// 脚本文件不输出最后的值，用退出码和错误信息检查执行结果
+	shebang := writeScript("shebang.bpl", "#!/usr/bin/env bubble\nlet x = 1;\n")
+	shebangError := writeScript("shebang_error.bpl", "#!/usr/bin/env bubble\nlet x = 1;\nx / 0\n")
+	checkArgs := writeScript("args.bpl", "match (args) {\n  [\"a\", \"b c\"] => 0,\n  _ => 1 / 0,\n}\n")
+	parseError := writeScript("parse.bpl", "let = 1;\n")
+	runtimeError := writeScript("runtime.bpl", "let x = 1;\nx + true\n")
+
+	tests := []struct {
+		arguments      []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string // 标准错误输出中应该包含的内容，为空时标准错误输出必须为空
+	}{
+		{[]string{shebang}, "", exitOK, "", ""},
+		{[]string{"run", shebang}, "", exitOK, "", ""},
+		{[]string{shebangError}, "", exitError, "", "shebang_error.bpl:3:1: division by zero"},
+		{[]string{checkArgs, "a", "b c"}, "", exitOK, "", ""},
+		{[]string{checkArgs, "a"}, "", exitError, "", "division by zero"},
+		{[]string{parseError}, "", exitError, "", "parse.bpl:1:5"},
+		{[]string{runtimeError}, "", exitError, "", "runtime.bpl:2:1: type mismatch: INTEGER + BOOLEAN"},
+		{[]string{filepath.Join(dir, "missing.bpl")}, "", exitError, "", "missing.bpl"},
+		{[]string{"run", "-"}, "#!/usr/bin/env bubble\n1 + 2", exitOK, "", ""},
+		{[]string{"run", "-"}, "1 +", exitError, "", "<stdin>:1:"},
+		{nil, "[1][\"a\"]", exitError, "", "<stdin>:1:"},
+		{[]string{"run"}, "", exitUsage, "", "missing script file"},
+
+		// -e 输出程序最后的值，null不输出
+		{[]string{"-e", "1 + 2"}, "", exitOK, "3\n", ""},
+		{[]string{"eval", "-e", "[1, 2]"}, "", exitOK, "[1, 2]\n", ""},
+		{[]string{"-e", "args", "x", "y z"}, "", exitOK, "[x, y z]\n", ""},
+		{[]string{"-e", "len(args)"}, "", exitOK, "0\n", ""},
+		{[]string{"-e", "if (false) { 1 }"}, "", exitOK, "", ""},
+		{[]string{"-e", "let x = 1;"}, "", exitOK, "", ""},
+		{[]string{"-e", "let = 1;"}, "", exitError, "", "<eval>:1:5"},
+		{[]string{"-e", "1 / 0"}, "", exitError, "", "<eval>:1:1: division by zero"},
+		{[]string{"eval"}, "", exitUsage, "", "missing -e <code>"},
+
+		// --checked 对后面的子命令都生效
+		{[]string{"-e", "9223372036854775807 + 1"}, "", exitOK, "9223372036854775808\n", ""},
+		{[]string{"--checked", "-e", "9223372036854775807 + 1"}, "", exitError, "", "integer overflow: 9223372036854775807 + 1"},
+		{[]string{"-checked", "run", "-"}, "-(-9223372036854775807 - 1)", exitError, "", "integer overflow: -(-9223372036854775808)"},
+		{[]string{"--checked", "-e", "9223372036854775806 + 1"}, "", exitOK, "9223372036854775807\n", ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		c := &command{stdin: strings.NewReader(tt.stdin), stdout: &stdout, stderr: &stderr}
+		code := c.run(tt.arguments)
+		if code != tt.expectedCode {
+			t.Errorf("%q: wrong exit code. expected=%d, got=%d (stderr=%q)", tt.arguments, tt.expectedCode, code, stderr.String())
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("%q: wrong stdout. expected=%q, got=%q", tt.arguments, tt.expectedStdout, stdout.String())
+		}
+		if tt.expectedStderr == "" && stderr.Len() != 0 {
+			t.Errorf("%q: unexpected stderr %q", tt.arguments, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), tt.expectedStderr) {
+			t.Errorf("%q: stderr does not contain %q. got=%q", tt.arguments, tt.expectedStderr, stderr.String())
+		}
+	}
+}
@@ -1,8 +1,22 @@
 package object
 
+import (
+	"errors"
+	"sort"
+)
+
+// 给变量赋值时的错误
+var (
+	ErrUndeclared = errors.New("undeclared variable")
+	ErrConstant   = errors.New("constant")
+)
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store     map[string]Object
+	constants map[string]bool // 通过const声明的名字
+	outer     *Environment
+	// checked 为true时整数运算的结果超出int64范围会报告溢出错误，而不是转换成大整数
+	checked bool
 }
 
 func NewEnvironment() *Environment {
@@ -11,15 +25,56 @@ func NewEnvironment() *Environment {
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return &Environment{
-		store: make(map[string]Object),
-		outer: outer,
+		store:   make(map[string]Object),
+		outer:   outer,
+		checked: outer.checked,
 	}
 }
+
+// SetCheckedArithmetic 设置整数运算是否检查溢出，之后创建的内层作用域会继承这个设置
+func (e *Environment) SetCheckedArithmetic(checked bool) {
+	e.checked = checked
+}
+
+// CheckedArithmetic 整数运算是否检查溢出
+func (e *Environment) CheckedArithmetic() bool {
+	return e.checked
+}
+
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return e.store[name]
 }
 
+// SetConst 在当前作用域中声明一个不能再赋值的常量
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+	return e.Set(name, val)
+}
+
+// IsConst 判断name是否是当前作用域中声明的常量，不包括外层作用域
+func (e *Environment) IsConst(name string) bool {
+	return e.constants[name]
+}
+
+// Assign 修改离当前作用域最近的name的绑定，name没有声明时返回ErrUndeclared，是常量时返回ErrConstant
+func (e *Environment) Assign(name string, val Object) error {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; !ok {
+			continue
+		}
+		if env.constants[name] {
+			return ErrConstant
+		}
+		env.store[name] = val
+		return nil
+	}
+	return ErrUndeclared
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -27,3 +82,13 @@ func (e *Environment) Get(name string) (Object, bool) {
 	}
 	return obj, ok
 }
+
+// Names 返回当前作用域中绑定的所有名字，按字母顺序排列，不包括外层作用域
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
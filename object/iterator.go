@@ -0,0 +1,167 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"unicode/utf8"
+)
+
+// Iterable 可以用 for (x in c) 遍历的对象。脚本中的集合通过哈希表的iter和next方法遍历，由求值器处理
+type Iterable interface {
+	Object
+	Iterator() Iterator
+}
+
+// Iterator 依次返回集合中的元素，没有更多元素时ok为false。
+// for (x in c) 只绑定value，for (k, v in c) 同时绑定key和value
+type Iterator interface {
+	Next() (key, value Object, ok bool)
+}
+
+// Range range()返回的整数序列，不包括Stop，Step不为0
+type Range struct {
+	Start, Stop, Step int64
+}
+
+func (r *Range) Type() ObjectType {
+	return RANGE_OBJ
+}
+
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+// Len 序列中元素的个数。int64范围内的序列最多有 2^64-1 个元素，所以用uint64计算，避免溢出
+func (r *Range) Len() uint64 {
+	switch {
+	case r.Step > 0 && r.Start < r.Stop:
+		return (uint64(r.Stop)-uint64(r.Start)-1)/uint64(r.Step) + 1
+	case r.Step < 0 && r.Start > r.Stop:
+		return (uint64(r.Start)-uint64(r.Stop)-1)/(0-uint64(r.Step)) + 1
+	default:
+		return 0
+	}
+}
+
+func (r *Range) Iterator() Iterator {
+	return &rangeIterator{next: r.Start, step: r.Step, remaining: r.Len()}
+}
+
+// rangeIterator 每次在当前值上加Step，不用 Start + index*Step，避免乘法溢出
+type rangeIterator struct {
+	next, step int64
+	remaining  uint64
+	index      int64
+}
+
+func (it *rangeIterator) Next() (Object, Object, bool) {
+	if it.remaining == 0 {
+		return nil, nil, false
+	}
+	key := &Integer{Value: it.index}
+	value := &Integer{Value: it.next}
+	it.remaining--
+	it.index++
+	// 最后一个元素之后的值可能溢出，但不会再被使用
+	it.next += it.step
+	return key, value, true
+}
+
+// Iterator 按下标遍历数组，循环中对数组的修改对后面的元素可见
+func (a *Array) Iterator() Iterator {
+	return &arrayIterator{a: a}
+}
+
+type arrayIterator struct {
+	a     *Array
+	index int
+}
+
+func (it *arrayIterator) Next() (Object, Object, bool) {
+	if it.index >= len(it.a.Elements) {
+		return nil, nil, false
+	}
+	key := &Integer{Value: int64(it.index)}
+	value := it.a.Elements[it.index]
+	it.index++
+	return key, value, true
+}
+
+// Iterator 按字符遍历字符串，key是字符的下标
+func (s *String) Iterator() Iterator {
+	return &stringIterator{s: s.Value}
+}
+
+type stringIterator struct {
+	s      string
+	offset int
+	index  int64
+}
+
+func (it *stringIterator) Next() (Object, Object, bool) {
+	if it.offset >= len(it.s) {
+		return nil, nil, false
+	}
+	r, size := utf8.DecodeRuneInString(it.s[it.offset:])
+	key := &Integer{Value: it.index}
+	it.offset += size
+	it.index++
+	return key, &String{Value: string(r)}, true
+}
+
+// Iterator 按键的顺序遍历哈希表开始遍历时的键值对
+func (h *Hash) Iterator() Iterator {
+	return &hashIterator{pairs: h.SortedPairs()}
+}
+
+type hashIterator struct {
+	pairs []HashPair
+	index int
+}
+
+func (it *hashIterator) Next() (Object, Object, bool) {
+	if it.index >= len(it.pairs) {
+		return nil, nil, false
+	}
+	pair := it.pairs[it.index]
+	it.index++
+	return pair.Key, pair.Value, true
+}
+
+// SortedPairs 按键排序的键值对：不同类型的键按类型名排序，数字按大小，字符串按字典序，false在true之前
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+func lessKey(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+	switch a := a.(type) {
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	case *Float:
+		return a.Value < b.(*Float).Value
+	case *Integer, *BigInt:
+		return integerValue(a).Cmp(integerValue(b)) < 0
+	default:
+		return a.Inspect() < b.Inspect()
+	}
+}
+
+func integerValue(obj Object) *big.Int {
+	if i, ok := obj.(*Integer); ok {
+		return big.NewInt(i.Value)
+	}
+	return obj.(*BigInt).Value
+}
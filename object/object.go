@@ -2,9 +2,13 @@ package object
 
 import (
 	"BubblePL/ast"
+	"BubblePL/token"
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -12,15 +16,19 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNTION_OBJ      = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
 )
 
 type BuiltinFunction func(args ...Object) Object
@@ -50,6 +58,55 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// BigInt 超出int64范围的整数，类型和Integer一样是INTEGER。
+// 求值器保证能放进int64的整数总是使用Integer表示
+type BigInt struct {
+	Value *big.Int
+}
+
+func (bi *BigInt) Inspect() string {
+	return bi.Value.String()
+}
+
+func (bi *BigInt) Type() ObjectType {
+	return INTEGER_OBJ
+}
+
+// bigIntKeyType BigInt的哈希键使用的类型。BigInt的值不会在int64范围内，
+// 和Integer的键使用不同的类型，避免哈希值和某个int64相同时冲突
+const bigIntKeyType ObjectType = "BIGINT"
+
+func (bi *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(bi.Value.String()))
+	return HashKey{Type: bigIntKeyType, Value: h.Sum64()}
+}
+
+type Float struct {
+	Value float64
+}
+
+// Inspect 使用能精确表示数值的最短形式，整数值保留 .0 以便和整数区分
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+func (f *Float) HashKey() HashKey {
+	// 0.0 和 -0.0 相等，使用同一个键
+	if f.Value == 0 {
+		return HashKey{Type: f.Type(), Value: 0}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -94,8 +151,51 @@ func (rv *ReturnValue) Type() ObjectType {
 	return RETURN_VALUE_OBJ
 }
 
+// Break break语句产生的信号，和ReturnValue一样沿着块向外传递，直到最内层的循环
+type Break struct{}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+// Continue continue语句产生的信号，传递到最内层的循环后开始下一次循环
+type Continue struct{}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
 type Error struct {
 	Message string
+	Pos     token.Position // 产生错误的节点的位置
+	Stack   []Frame        // 错误传播时经过的函数调用，最内层的调用在最前面
+}
+
+// Frame 调用栈中的一次函数调用
+type Frame struct {
+	Function string         // 函数名，匿名函数为空
+	Pos      token.Position // 调用的位置
+	Args     int            // 参数的个数
+}
+
+func (f Frame) String() string {
+	name := f.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	unit := "arguments"
+	if f.Args == 1 {
+		unit = "argument"
+	}
+	return fmt.Sprintf("%s (%d %s) called at %s", name, f.Args, unit, f.Pos)
 }
 
 func (e *Error) Type() ObjectType {
@@ -103,11 +203,26 @@ func (e *Error) Type() ObjectType {
 }
 
 func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Message
+	}
 	return e.Message
 }
 
+// StackTrace 输出错误信息以及错误经过的调用栈
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+	out.WriteString(e.Inspect())
+	for _, frame := range e.Stack {
+		out.WriteString("\n    in ")
+		out.WriteString(frame.String())
+	}
+	return out.String()
+}
+
 type Function struct {
-	Parameters []*ast.Identifier
+	Name       string // 通过let绑定时的名字，匿名函数为空
+	Parameters []*ast.Parameter
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := String{Value: "hello"}
@@ -18,3 +22,109 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+		{1e-9, "1e-09"},
+		{0.30000000000000004, "0.30000000000000004"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("Float{%g}.Inspect() wrong. expected=%q, got=%q", tt.value, tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	big1, _ := new(big.Int).SetString("99999999999999999999", 10)
+	big2, _ := new(big.Int).SetString("99999999999999999999", 10)
+	diff, _ := new(big.Int).SetString("99999999999999999998", 10)
+
+	if (&BigInt{Value: big1}).HashKey() != (&BigInt{Value: big2}).HashKey() {
+		t.Errorf("bigints with same value have different hash keys")
+	}
+	if (&BigInt{Value: big1}).HashKey() == (&BigInt{Value: diff}).HashKey() {
+		t.Errorf("bigints with different value have same hash keys")
+	}
+
+	// 哈希值和某个int64相同的大整数不能和这个int64冲突
+	shifted := new(big.Int).Lsh(big.NewInt(1), 70)
+	key := (&BigInt{Value: shifted}).HashKey()
+	if key == (&Integer{Value: int64(key.Value)}).HashKey() {
+		t.Errorf("bigint %s has the same hash key as integer %d", shifted, int64(key.Value))
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r        Range
+		expected uint64
+	}{
+		{Range{0, 10, 1}, 10},
+		{Range{0, 10, 3}, 4},
+		{Range{10, 0, -1}, 10},
+		{Range{10, 0, -4}, 3},
+		{Range{5, 5, 1}, 0},
+		{Range{5, 0, 1}, 0},
+		{Range{0, 5, -1}, 0},
+		// 超出int64的跨度
+		{Range{-5, math.MaxInt64, math.MaxInt64}, 2},
+		{Range{-math.MaxInt64, math.MaxInt64, 1 << 62}, 4},
+		{Range{math.MinInt64, math.MaxInt64, 1}, math.MaxUint64},
+		{Range{math.MaxInt64, math.MinInt64, math.MinInt64}, 2},
+		{Range{math.MaxInt64 - 1, math.MaxInt64, 1}, 1},
+	}
+
+	for _, tt := range tests {
+		if tt.r.Len() != tt.expected {
+			t.Errorf("%s.Len() wrong. expected=%d, got=%d", tt.r.Inspect(), tt.expected, tt.r.Len())
+		}
+	}
+}
+
+func TestIterators(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range []Object{&String{Value: "b"}, &Integer{Value: 2}, &String{Value: "a"}, &Boolean{Value: true}, &Integer{Value: -1}} {
+		hash.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: key}
+	}
+
+	tests := []struct {
+		iterable Iterable
+		expected string
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 5}, &String{Value: "x"}}}, "0:5 1:x "},
+		{&String{Value: "añb"}, "0:a 1:ñ 2:b "},
+		{&Range{Start: 1, Stop: 7, Step: 2}, "0:1 1:3 2:5 "},
+		{&Range{Start: -5, Stop: math.MaxInt64, Step: math.MaxInt64}, "0:-5 1:9223372036854775802 "},
+		{&Range{Start: -math.MaxInt64, Stop: math.MaxInt64, Step: 1 << 62}, "0:-9223372036854775807 1:-4611686018427387903 2:1 3:4611686018427387905 "},
+		{&Range{Start: math.MaxInt64, Stop: math.MinInt64, Step: math.MinInt64}, "0:9223372036854775807 1:-1 "},
+		{hash, "true:true -1:-1 2:2 a:a b:b "},
+		{&Array{}, ""},
+	}
+
+	for _, tt := range tests {
+		var out string
+		it := tt.iterable.Iterator()
+		for {
+			key, value, ok := it.Next()
+			if !ok {
+				break
+			}
+			out += key.Inspect() + ":" + value.Inspect() + " "
+		}
+		if out != tt.expected {
+			t.Errorf("%s: wrong iteration. expected=%q, got=%q", tt.iterable.Type(), tt.expected, out)
+		}
+	}
+}
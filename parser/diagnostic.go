@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"BubblePL/token"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Severity 诊断信息的严重程度
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
+
+// 诊断代码，同一类问题使用同一个代码，方便查找和过滤
+const (
+	CodeUnexpectedToken    = "P0001" // 出现了不符合语法的Token
+	CodeExpectedExpression = "P0002" // 需要一个表达式
+	CodeInvalidInteger     = "P0003" // 整数字面量无法解析
+	CodeUnclosedDelimiter  = "P0004" // 括号没有闭合
+	CodeInvalidFloat       = "P0005" // 浮点数字面量无法解析
+	CodeInvalidParameter   = "P0006" // 函数参数列表不合法
+	CodeInvalidArgument    = "P0007" // 调用参数列表不合法
+	CodeInvalidAssignment  = "P0008" // 赋值的左边不是变量
+	CodeOutsideLoop        = "P0009" // break或者continue不在循环中
+	CodeInvalidPattern     = "P0010" // 模式不合法
+)
+
+// Diagnostic 解析过程中产生的诊断信息
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Pos      token.Position // 问题开始的位置
+	End      token.Position // 问题结束的位置
+	Hints    []string       // 修复问题的提示
+}
+
+// Error 按照 位置: 严重程度[代码]: 信息 的格式输出诊断信息
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s[%s]: %s", d.Pos, d.Severity, d.Code, d.Message)
+}
+
+// Format 输出诊断信息、出问题的源代码行、指向问题位置的标记以及提示
+func (d *Diagnostic) Format(source string) string {
+	var out bytes.Buffer
+	out.WriteString(d.Error())
+	out.WriteString("\n")
+
+	lines := strings.Split(source, "\n")
+	if d.Pos.IsValid() && d.Pos.Line <= len(lines) {
+		line := strings.TrimRight(lines[d.Pos.Line-1], "\r")
+		out.WriteString("    " + line + "\n")
+		out.WriteString("    ")
+		// 列号按字符计数，保留缩进中的制表符，保证标记和源代码对齐
+		runes := []rune(line)
+		for i := 0; i < d.Pos.Column-1 && i < len(runes); i++ {
+			if runes[i] == '\t' {
+				out.WriteByte('\t')
+			} else {
+				out.WriteByte(' ')
+			}
+		}
+		width := 1
+		if d.End.Line == d.Pos.Line && d.End.Column > d.Pos.Column {
+			width = d.End.Column - d.Pos.Column
+		}
+		out.WriteString(strings.Repeat("^", width))
+		out.WriteString("\n")
+	}
+	for _, hint := range d.Hints {
+		out.WriteString("    hint: " + hint + "\n")
+	}
+	return out.String()
+}
+
+// describeTokenType 返回TokenType的可读描述
+func describeTokenType(tokenType token.TokenType) string {
+	switch tokenType {
+	case token.EOF:
+		return "end of input"
+	case token.IDENT:
+		return "identifier"
+	case token.INT:
+		return "integer"
+	case token.FLOAT:
+		return "float"
+	case token.STRING, token.INTERP_START:
+		return "string"
+	case token.INTERP_MID, token.INTERP_END:
+		return `"}"`
+	case token.EQ:
+		return `"=="`
+	case token.NOT_EQ:
+		return `"!="`
+	case token.LT_EQ:
+		return `"<="`
+	case token.GT_EQ:
+		return `">="`
+	case token.POWER:
+		return `"**"`
+	case token.AND:
+		return `"&&"`
+	case token.OR:
+		return `"||"`
+	case token.SHL:
+		return `"<<"`
+	case token.SHR:
+		return `">>"`
+	}
+	for literal, keyword := range token.KeywordsMap {
+		if keyword == tokenType {
+			return fmt.Sprintf("%q", literal)
+		}
+	}
+	return fmt.Sprintf("%q", string(tokenType))
+}
+
+// describeToken 返回Token的可读描述，标识符和字面量会带上具体的值
+func describeToken(tk token.Token) string {
+	switch tk.Type {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING:
+		return fmt.Sprintf("%s %q", describeTokenType(tk.Type), tk.Literal)
+	case token.ILLEGAL:
+		return fmt.Sprintf("invalid token %q", tk.Literal)
+	default:
+		return describeTokenType(tk.Type)
+	}
+}
@@ -5,32 +5,65 @@ import (
 	"BubblePL/lexer"
 	"BubblePL/token"
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
-	EQUALS
-	LESSGREATER
-	SUM
-	PRODUCT
-	PREFIX
+	ASSIGN      // = += -= *= /=，右结合
+	CONDITIONAL // ?:，右结合
+	NULLISH     // ??
+	OR          // ||
+	AND         // &&
+	EQUALS      // == !=
+	LESSGREATER // < > <= >=
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << >>
+	SUM         // + -
+	PRODUCT     // * / %
+	PREFIX      // -x !x ~x
+	POWER       // **，比前缀运算符优先，-2 ** 2 == -4
 	CALL
 	INDEX
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:            ASSIGN,
+	token.PLUS_ASSIGN:       ASSIGN,
+	token.MINUS_ASSIGN:      ASSIGN,
+	token.ASTERISK_ASSIGN:   ASSIGN,
+	token.SLASH_ASSIGN:      ASSIGN,
+	token.QUESTION:          CONDITIONAL,
+	token.NULLISH:           NULLISH,
+	token.OR:                OR,
+	token.AND:               AND,
+	token.EQ:                EQUALS,
+	token.NOT_EQ:            EQUALS,
+	token.LT:                LESSGREATER,
+	token.GT:                LESSGREATER,
+	token.LT_EQ:             LESSGREATER,
+	token.GT_EQ:             LESSGREATER,
+	token.BIT_OR:            BIT_OR,
+	token.BIT_XOR:           BIT_XOR,
+	token.BIT_AND:           BIT_AND,
+	token.SHL:               SHIFT,
+	token.SHR:               SHIFT,
+	token.PLUS:              SUM,
+	token.MINUS:             SUM,
+	token.SLASH:             PRODUCT,
+	token.ASTERISK:          PRODUCT,
+	token.PERCENT:           PRODUCT,
+	token.POWER:             POWER,
+	token.LPAREN:            CALL,
+	token.LBRACKET:          INDEX,
+	token.DOT:               INDEX,
+	token.OPTIONAL_DOT:      INDEX,
+	token.OPTIONAL_LBRACKET: INDEX,
 }
 
 type (
@@ -44,7 +77,21 @@ type Parser struct {
 	l         *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
-	errors    []string
+	errors    []*Diagnostic
+	// panicking 为true时说明当前语句已经出错，在同步到下一条语句之前不再报告新的错误
+	panicking bool
+	// depth 当前Token之前未闭合的 { 的数量
+	depth int
+	// loops 当前所在的循环的层数，进入函数体时重新从0开始
+	loops int
+	// pending 还没有归属到节点的注释
+	pending []token.Comment
+	// comments 注释和它们所属的节点
+	comments map[ast.Node][]token.Comment
+	// colonFollows 为true时下一个解析的表达式后面的 : 属于外层的哈希表或者条件表达式
+	colonFollows bool
+	// optionalIndex 当前语句中最近的 ?[ ，出现多余的 : 时用来提示 ?[ 被当作了可选索引
+	optionalIndex *token.Token
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -58,34 +105,127 @@ func (p *Parser) registerInfixFn(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*Diagnostic {
 	return p.errors
 }
 
+// report 记录一条错误，同一条语句只记录第一条错误，避免一个错误引发一连串的错误
+func (p *Parser) report(diagnostic *Diagnostic) {
+	if p.panicking {
+		return
+	}
+	p.errors = append(p.errors, diagnostic)
+	p.panicking = true
+}
+
+// errorAt 在Token的位置记录一条错误
+func (p *Parser) errorAt(tk token.Token, code string, msg string, hints ...string) {
+	p.report(&Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  msg,
+		Pos:      tk.Pos,
+		End:      tk.End,
+		Hints:    hints,
+	})
+}
+
+// synchronize 出错后跳过剩下的Token，直到回到语句开始时的 { } 嵌套层数level，
+// 并且当前Token是分号或者所在块的 }，或者下一个Token是 } 或者语句关键字
+func (p *Parser) synchronize(level int) {
+	p.panicking = false
+	for {
+		if p.depth == level && (p.curTokenIs(token.SEMICOLON) || p.curTokenIs(token.RBRACE)) {
+			return
+		}
+		if p.peekTokenIs(token.EOF) {
+			return
+		}
+		// depth 下一个Token之前未闭合的 { 的数量，当前Token是 { 或者 } 时和p.depth不同
+		depth := p.depth
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		}
+		if depth == level {
+			switch p.peekToken.Type {
+			case token.RBRACE, token.LET, token.CONST, token.RETURN, token.WHILE, token.FOR:
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) nextToken() {
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.depth++
+	case token.RBRACE:
+		p.depth--
+	}
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	p.pending = append(p.pending, p.curToken.Leading...)
+}
+
+// takeComments 取出目前为止还没有归属的注释
+func (p *Parser) takeComments() []token.Comment {
+	comments := p.pending
+	p.pending = nil
+	return comments
+}
+
+// attachComments 把注释归属到节点上
+func (p *Parser) attachComments(node ast.Node, comments []token.Comment) {
+	if len(comments) == 0 {
+		return
+	}
+	if p.comments == nil {
+		p.comments = make(map[ast.Node][]token.Comment)
+	}
+	p.comments[node] = append(p.comments[node], comments...)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 	for p.curToken.Type != token.EOF {
+		level := p.depth
+		comments := p.takeComments()
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(level)
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
+			p.attachComments(stmt, append(comments, p.takeComments()...))
 		}
 		p.nextToken()
 	}
+	// 文件末尾的注释归属于整个程序
+	p.attachComments(program, p.takeComments())
+	program.Comments = p.comments
 	return program
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	// 以 : 开头的语句一定是错误，保留上一条语句中的 ?[ 用来提示
+	if !p.curTokenIs(token.COLON) {
+		p.optionalIndex = nil
+	}
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -103,15 +243,33 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-func (p *Parser) noPrefixParseFnError(tokenType token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", tokenType)
-	p.errors = append(p.errors, msg)
+func (p *Parser) noPrefixParseFnError() {
+	if p.curTokenIs(token.ILLEGAL) {
+		p.illegalTokenError(p.curToken)
+		return
+	}
+	msg := fmt.Sprintf("expected an expression, but got %s", describeToken(p.curToken))
+	var hints []string
+	switch p.curToken.Type {
+	case token.RPAREN, token.RBRACKET, token.RBRACE:
+		hints = append(hints, fmt.Sprintf("%s has no matching opening delimiter", describeToken(p.curToken)))
+	case token.EOF:
+		hints = append(hints, "the input ended in the middle of an expression")
+	case token.COLON:
+		if p.optionalIndex != nil {
+			hints = append(hints, fmt.Sprintf(`"?[" at %s is an optional index, write "? [" with a space to start a conditional expression`, p.optionalIndex.Pos))
+		}
+	}
+	p.errorAt(p.curToken, CodeExpectedExpression, msg, hints...)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	// 只有这个表达式本身受colonFollows影响，里面嵌套的表达式不受影响
+	colonFollows := p.colonFollows
+	p.colonFollows = false
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
+		p.noPrefixParseFnError()
 		return nil
 	}
 	leftExp := prefix()
@@ -121,6 +279,11 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 			return leftExp
 		}
 		p.nextToken()
+		// leftExp 是完整的条件时 c ?[1] : [2] 可能是条件表达式
+		if p.curTokenIs(token.OPTIONAL_LBRACKET) && precedence < CONDITIONAL && !colonFollows {
+			leftExp = p.parseOptionalIndexOrConditional(leftExp)
+			continue
+		}
 		leftExp = infix(leftExp)
 	}
 
@@ -133,20 +296,29 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Name:  nil,
 		Value: nil,
 	}
-	if !p.expectedPeek(token.IDENT) {
-		return nil
-	}
-	letStmt.Name = &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
+	hint := fmt.Sprintf("%s statements have the form: %s <name> = <expression>;", letStmt.Token.Literal, letStmt.Token.Literal)
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		letStmt.Pattern = p.parsePattern()
+		if letStmt.Pattern == nil || !p.checkPatternNames(letStmt.Pattern, map[string]bool{}) {
+			return nil
+		}
+	} else {
+		if !p.expectedPeek(token.IDENT, hint) {
+			return nil
+		}
+		letStmt.Name = &ast.Identifier{
+			Token: p.curToken,
+			Value: p.curToken.Literal,
+		}
 	}
-	if !p.expectedPeek(token.ASSIGN) {
+	if !p.expectedPeek(token.ASSIGN, hint) {
 		return nil
 	}
 	p.nextToken()
 	letStmt.Value = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return letStmt
@@ -159,12 +331,138 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	}
 	p.nextToken()
 	returnStmt.ReturnValue = p.parseExpression(LOWEST)
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return returnStmt
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+	if !p.expectedPeek(token.LPAREN, "the condition of while must be wrapped in parentheses") {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectedClosing(token.RPAREN, open) {
+		return nil
+	}
+	if !p.expectedPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseForStatement 解析 for (init; cond; update) { }，三个部分都可以省略，分号不能省略。
+// 括号中以 x in 或者 k, v in 开始时是for-in循环
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+	if !p.expectedPeek(token.LPAREN, "for loops have the form: for (init; condition; update) { } or for (x in xs) { }") {
+		return nil
+	}
+	open := p.curToken
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	} else {
+		p.nextToken()
+		if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+			return p.parseForInStatement(stmt.Token, open)
+		}
+		switch p.curToken.Type {
+		case token.LET, token.CONST:
+			stmt.Init = p.parseLetStatement()
+		default:
+			stmt.Init = p.parseExpressionStatement()
+		}
+		if p.panicking {
+			return nil
+		}
+		if !p.curTokenIs(token.SEMICOLON) && !p.expectedPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectedPeek(token.SEMICOLON) {
+		return nil
+	}
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Update = p.parseExpression(LOWEST)
+	}
+	if !p.expectedClosing(token.RPAREN, open) {
+		return nil
+	}
+	if !p.expectedPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseForInStatement 解析for-in循环剩下的部分，当前Token是第一个循环变量
+func (p *Parser) parseForInStatement(tk, open token.Token) ast.Statement {
+	stmt := &ast.ForInStatement{Token: tk}
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectedPeek(token.IDENT, "for-in loops have the form: for (key, value in xs) { }") {
+			return nil
+		}
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectedPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if !p.expectedClosing(token.RPAREN, open) {
+		return nil
+	}
+	if !p.expectedPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseLoopBody()
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseLoopBody 解析循环体，循环体中可以使用break和continue
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	defer func() { p.loops-- }()
+	return p.parseBlockStatement()
+}
+
+// parseLoopControlStatement 解析break和continue，它们只能出现在循环中
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tk := p.curToken
+	if p.loops == 0 {
+		p.errorAt(tk, CodeOutsideLoop, fmt.Sprintf("%s outside of a loop", tk.Literal))
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if tk.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tk}
+	}
+	return &ast.ContinueStatement{Token: tk}
+}
+
 func (p *Parser) peekTokenIs(tokenType token.TokenType) bool {
 	return p.peekToken.Type == tokenType
 }
@@ -174,19 +472,54 @@ func (p *Parser) curTokenIs(tokenType token.TokenType) bool {
 }
 
 // expectedPeek 通过检查下一个词法单元的类型，确保词法单元顺序的正确性
-func (p *Parser) expectedPeek(tokenType token.TokenType) bool {
+func (p *Parser) expectedPeek(tokenType token.TokenType, hints ...string) bool {
 	if p.peekTokenIs(tokenType) {
 		p.nextToken()
 		return true
 	} else {
-		p.peekError(tokenType)
+		p.peekError(tokenType, hints...)
 		return false
 	}
 }
 
-func (p *Parser) peekError(tokenType token.TokenType) {
-	err := fmt.Sprintf("Parser error: expected=%q, but got=%q", tokenType, p.peekToken.Type)
-	p.errors = append(p.errors, err)
+// expectedClosing 检查下一个Token是否是与open匹配的闭合符号
+func (p *Parser) expectedClosing(tokenType token.TokenType, open token.Token) bool {
+	if p.peekTokenIs(tokenType) {
+		p.nextToken()
+		return true
+	}
+	if p.peekTokenIs(token.ILLEGAL) {
+		p.illegalTokenError(p.peekToken)
+		return false
+	}
+	msg := fmt.Sprintf("expected %s, but got %s", describeTokenType(tokenType), describeToken(p.peekToken))
+	hint := fmt.Sprintf("to match %s at %s", describeTokenType(open.Type), open.Pos)
+	p.errorAt(p.peekToken, CodeUnclosedDelimiter, msg, hint)
+	return false
+}
+
+func (p *Parser) peekError(tokenType token.TokenType, hints ...string) {
+	if p.peekTokenIs(token.ILLEGAL) {
+		p.illegalTokenError(p.peekToken)
+		return
+	}
+	msg := fmt.Sprintf("expected %s, but got %s", describeTokenType(tokenType), describeToken(p.peekToken))
+	p.errorAt(p.peekToken, CodeUnexpectedToken, msg, hints...)
+}
+
+// illegalTokenError 报告ILLEGAL Token对应的词法错误
+func (p *Parser) illegalTokenError(tk token.Token) {
+	if err, ok := p.l.ErrorFor(tk); ok {
+		p.report(&Diagnostic{
+			Severity: SeverityError,
+			Code:     err.Code,
+			Message:  err.Message,
+			Pos:      err.Pos,
+			End:      err.End,
+		})
+		return
+	}
+	p.errorAt(tk, CodeUnexpectedToken, fmt.Sprintf("unexpected %s", describeToken(tk)))
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
@@ -201,14 +534,46 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 		Token: p.curToken,
 		Value: 0,
 	}
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	// 词法分析已经检查过 _ 的位置，这里直接去掉
+	literal, base := integerDigits(strings.ReplaceAll(p.curToken.Literal, "_", ""))
+	value, err := strconv.ParseInt(literal, base, 64)
+	if err == nil {
+		exp.Value = value
+		return exp
+	}
+	// 超出int64范围的字面量使用大整数
+	if bigValue, ok := new(big.Int).SetString(literal, base); ok {
+		exp.Big = bigValue
+		return exp
+	}
+	msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
+	p.errorAt(p.curToken, CodeInvalidInteger, msg)
+	return nil
+}
+
+// integerDigits 去掉整数字面量的 0x、0o、0b 前缀，返回剩下的数字和进制，没有前缀时是十进制
+func integerDigits(literal string) (string, int) {
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			return literal[2:], 16
+		case 'o', 'O':
+			return literal[2:], 8
+		case 'b', 'B':
+			return literal[2:], 2
+		}
+	}
+	return literal, 10
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.errorAt(p.curToken, CodeInvalidFloat, msg, "the value is out of the range of a 64-bit float")
 		return nil
 	}
-	exp.Value = value
-	return exp
+	return &ast.FloatLiteral{Token: p.curToken, Value: value}
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -244,11 +609,100 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 	precedence := p.curPrecedence()
+	// ** 是右结合的，右边的 ** 先计算
+	if p.curTokenIs(token.POWER) {
+		precedence--
+	}
 	p.nextToken()
 	exp.Right = p.parseExpression(precedence)
 	return exp
 }
 
+// parseAssignExpression 解析赋值表达式，赋值是右结合的，a = b = 1 先给b赋值
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+	if target == nil {
+		return nil
+	}
+	switch target := target.(type) {
+	case *ast.Identifier:
+	case *ast.IndexExpression, *ast.DotExpression:
+		if isOptionalChain(target) {
+			p.report(&Diagnostic{
+				Severity: SeverityError,
+				Code:     CodeInvalidAssignment,
+				Message:  fmt.Sprintf("cannot assign to optional chain %s", target.String()),
+				Pos:      target.Pos(),
+				End:      target.End(),
+				Hints:    []string{`use "." and "[]" to assign to fields and indexes`},
+			})
+			return nil
+		}
+	default:
+		p.report(&Diagnostic{
+			Severity: SeverityError,
+			Code:     CodeInvalidAssignment,
+			Message:  fmt.Sprintf("cannot assign to %s", target.String()),
+			Pos:      target.Pos(),
+			End:      target.End(),
+			Hints:    []string{"only variables, indexes and fields can be assigned"},
+		})
+		return nil
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN - 1)
+	return exp
+}
+
+// isOptionalChain 访问链中是否有 ?. 或者 ?[
+func isOptionalChain(exp ast.Expression) bool {
+	for {
+		switch e := exp.(type) {
+		case *ast.DotExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Left
+		case *ast.IndexExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Left
+		case *ast.MethodCallExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Object
+		default:
+			return false
+		}
+	}
+}
+
+// parseConditionalExpression 解析 cond ? a : b，右结合，a ? b : c ? d : e 相当于 a ? b : (c ? d : e)
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{
+		Token:     p.curToken,
+		Condition: condition,
+	}
+	p.nextToken()
+	p.colonFollows = true
+	exp.Consequence = p.parseExpression(LOWEST)
+	if exp.Consequence == nil || !p.expectedPeek(token.COLON, "conditional expressions have the form: <condition> ? <expression> : <expression>") {
+		return nil
+	}
+	p.nextToken()
+	exp.Alternative = p.parseExpression(CONDITIONAL - 1)
+	if exp.Alternative == nil {
+		return nil
+	}
+	return exp
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{
 		Token: p.curToken,
@@ -257,10 +711,11 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	open := p.curToken
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
-	if !p.expectedPeek(token.RPAREN) {
+	if !p.expectedClosing(token.RPAREN, open) {
 		return nil
 	}
 	return exp
@@ -275,12 +730,30 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		level := p.depth
+		comments := p.takeComments()
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(level)
+			// 同步时停在了块的 } 上
+			if p.curTokenIs(token.RBRACE) {
+				break
+			}
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
+			p.attachComments(stmt, append(comments, p.takeComments()...))
 		}
 		p.nextToken()
 	}
+	// 块末尾的注释归属于整个块
+	p.attachComments(block, p.takeComments())
+	if p.curTokenIs(token.RBRACE) {
+		block.RBrace = p.curToken
+	} else {
+		p.errorAt(p.curToken, CodeUnclosedDelimiter,
+			fmt.Sprintf("expected %s, but got %s", describeTokenType(token.RBRACE), describeToken(p.curToken)),
+			fmt.Sprintf("to match %s at %s", describeTokenType(token.LBRACE), block.Token.Pos))
+	}
 	return block
 }
 
@@ -291,12 +764,13 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		Consequence: nil,
 		Alternative: nil,
 	}
-	if !p.expectedPeek(token.LPAREN) {
+	if !p.expectedPeek(token.LPAREN, "the condition of if must be wrapped in parentheses") {
 		return nil
 	}
+	open := p.curToken
 	p.nextToken()
 	exp.Condition = p.parseExpression(LOWEST)
-	if !p.expectedPeek(token.RPAREN) {
+	if !p.expectedClosing(token.RPAREN, open) {
 		return nil
 	}
 	if !p.expectedPeek(token.LBRACE) {
@@ -306,7 +780,21 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
-		if !p.expectedPeek(token.LBRACE) {
+		// else if 相当于else块中只有一个if表达式
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			tk := p.curToken
+			alternative := p.parseIfExpression()
+			if alternative == nil {
+				return nil
+			}
+			exp.Alternative = &ast.BlockStatement{
+				Token:      tk,
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: tk, Expression: alternative}},
+			}
+			return exp
+		}
+		if !p.expectedPeek(token.LBRACE, `else must be followed by "{" or "if"`) {
 			return nil
 		}
 		exp.Alternative = p.parseBlockStatement()
@@ -314,31 +802,307 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return exp
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	var identifiers []*ast.Identifier
-	if p.peekTokenIs(token.RPAREN) {
+// parseMatchExpression 解析 match (value) { pattern if guard => result, ... }，分支之间用逗号分隔，
+// 分支的结果是块时可以省略逗号
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectedPeek(token.LPAREN, "the value of match must be wrapped in parentheses") {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectedClosing(token.RPAREN, open) {
+		return nil
+	}
+	if !p.expectedPeek(token.LBRACE) {
+		return nil
+	}
+	brace := p.curToken
+	for !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) && !p.curTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA, `separate match arms with ","`)
+			return nil
+		}
+	}
+	if !p.expectedClosing(token.RBRACE, brace) {
+		return nil
+	}
+	exp.RBrace = p.curToken
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil || !p.checkPatternNames(arm.Pattern, map[string]bool{}) {
+		return nil
+	}
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
 		p.nextToken()
-		return identifiers
+		arm.Guard = p.parseExpression(LOWEST)
 	}
+	if !p.expectedPeek(token.FAT_ARROW, "match arms have the form: pattern => result") {
+		return nil
+	}
+	arm.Token = p.curToken
 	p.nextToken()
-	ident := &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
 	}
-	identifiers = append(identifiers, ident)
-	for p.peekTokenIs(token.COMMA) {
+	tk := p.curToken
+	body := p.parseExpression(LOWEST)
+	if body == nil {
+		return nil
+	}
+	arm.Body = &ast.BlockStatement{
+		Token:      tk,
+		Statements: []ast.Statement{&ast.ExpressionStatement{Token: tk, Expression: body}},
+	}
+	return arm
+}
+
+// parsePattern 解析一个模式：_、变量、字面量、[a, ...rest] 或者 {"k": v}
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.BindingPattern{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		value := p.prefixParseFns[p.curToken.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Value: value}
+	case token.MINUS:
+		if p.peekTokenIs(token.INT) || p.peekTokenIs(token.FLOAT) {
+			return &ast.LiteralPattern{Value: p.parsePrefixExpression()}
+		}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	case token.ILLEGAL:
+		p.illegalTokenError(p.curToken)
+		return nil
+	}
+	p.errorAt(p.curToken, CodeInvalidPattern, fmt.Sprintf("expected a pattern, but got %s", describeToken(p.curToken)),
+		`patterns are _, names, literals, [a, ...rest] or {"key": pattern}`)
+	return nil
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACKET) {
 		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectedPeek(token.IDENT, "the rest of an array pattern must be a name") {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.errorAt(p.peekToken, CodeInvalidPattern, "elements after the rest of an array pattern",
+					"...rest must be the last element")
+				return nil
+			}
+			break
+		}
+		element := p.parsePatternElement(p.parsePattern())
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectedPeek(token.COMMA, `separate elements with "," or close the pattern with "]"`) {
+			return nil
+		}
+	}
+	if !p.expectedClosing(token.RBRACKET, pattern.Token) {
+		return nil
+	}
+	pattern.RBracket = p.curToken
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		var key ast.Expression
+		var value ast.Pattern
+		switch p.curToken.Type {
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			key = p.prefixParseFns[p.curToken.Type]()
+			if key == nil || !p.expectedPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			value = p.parsePatternElement(p.parsePattern())
+		case token.IDENT:
+			if p.peekTokenIs(token.COLON) {
+				p.errorAt(p.curToken, CodeInvalidPattern,
+					fmt.Sprintf("expected a string, integer or boolean key, but got %s", describeToken(p.curToken)),
+					fmt.Sprintf(`use {"%s": pattern}, or {%s} to bind the key "%s" to %s`,
+						p.curToken.Literal, p.curToken.Literal, p.curToken.Literal, p.curToken.Literal))
+				return nil
+			}
+			// 简写 {name} 等价于 {"name": name}
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+			name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			value = p.parsePatternElement(&ast.BindingPattern{Name: name})
+		default:
+			p.errorAt(p.curToken, CodeInvalidPattern,
+				fmt.Sprintf("expected a string, integer or boolean key, but got %s", describeToken(p.curToken)))
+			return nil
+		}
+		if value == nil {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectedPeek(token.COMMA, `separate pairs with "," or close the pattern with "}"`) {
+			return nil
+		}
+	}
+	if !p.expectedClosing(token.RBRACE, pattern.Token) {
+		return nil
+	}
+	pattern.RBrace = p.curToken
+	return pattern
+}
+
+// parsePatternElement 解析数组或者哈希表模式中的元素之后可选的默认值 = expression，只有变量可以带默认值
+func (p *Parser) parsePatternElement(pattern ast.Pattern) ast.Pattern {
+	if pattern == nil || !p.peekTokenIs(token.ASSIGN) {
+		return pattern
+	}
+	binding, ok := pattern.(*ast.BindingPattern)
+	if !ok {
+		p.errorAt(p.peekToken, CodeInvalidPattern, fmt.Sprintf("%s cannot have a default value", pattern.String()),
+			"only names in a pattern can have default values")
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+	binding.Default = p.parseExpression(LOWEST)
+	if binding.Default == nil {
+		return nil
+	}
+	return binding
+}
+
+// checkPatternNames 检查模式中的变量没有重复，也没有和seen中已有的变量重复
+func (p *Parser) checkPatternNames(pattern ast.Pattern, seen map[string]bool) bool {
+	for _, name := range ast.PatternNames(pattern) {
+		if seen[name.Value] {
+			p.errorAt(name.Token, CodeInvalidPattern, fmt.Sprintf("%s is bound more than once", name.Value))
+			return false
+		}
+		seen[name.Value] = true
+	}
+	return true
+}
+
+// parseFunctionParameters 解析参数列表，带默认值的参数只能在普通参数之后，...rest 只能是最后一个参数
+func (p *Parser) parseFunctionParameters() []*ast.Parameter {
+	var params []*ast.Parameter
+	open := p.curToken
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return params
+	}
+	seen := make(map[string]bool)
+	for {
+		param := p.parseFunctionParameter()
+		if param == nil {
+			return nil
+		}
+		if n := len(params); n > 0 {
+			prev := params[n-1]
+			switch {
+			case prev.Rest:
+				p.errorAt(param.Token, CodeInvalidParameter, "parameter after rest parameter",
+					"the rest parameter must be the last parameter")
+				return nil
+			case prev.Default != nil && param.Default == nil && !param.Rest:
+				p.errorAt(param.Token, CodeInvalidParameter,
+					fmt.Sprintf("parameter %s without default value follows a parameter with default value", param.String()))
+				return nil
+			}
+		}
+		if param.Pattern != nil {
+			if !p.checkPatternNames(param.Pattern, seen) {
+				return nil
+			}
+		} else {
+			if seen[param.Name.Value] {
+				p.errorAt(param.Name.Token, CodeInvalidParameter, fmt.Sprintf("duplicate parameter %s", param.Name.Value))
+				return nil
+			}
+			seen[param.Name.Value] = true
+		}
+		params = append(params, param)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		ident := &ast.Identifier{
+	}
+	if !p.expectedClosing(token.RPAREN, open) {
+		return nil
+	}
+	return params
+}
+
+// parseFunctionParameter 解析一个参数：x、x = 默认值、...rest 或者解构参数 [a, b]、{name}
+func (p *Parser) parseFunctionParameter() *ast.Parameter {
+	param := &ast.Parameter{}
+	if p.peekTokenIs(token.ELLIPSIS) {
+		p.nextToken()
+		param.Token = p.curToken
+		param.Rest = true
+	} else if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		param.Token = p.curToken
+		param.Pattern = p.parsePattern()
+		if param.Pattern == nil {
+			return nil
+		}
+	}
+	if param.Pattern == nil {
+		if !p.expectedPeek(token.IDENT, "function parameters must be identifiers or [a, b] and {name} patterns") {
+			return nil
+		}
+		if !param.Rest {
+			param.Token = p.curToken
+		}
+		param.Name = &ast.Identifier{
 			Token: p.curToken,
 			Value: p.curToken.Literal,
 		}
-		identifiers = append(identifiers, ident)
 	}
-	if !p.expectedPeek(token.RPAREN) {
-		return nil
+	if p.peekTokenIs(token.ASSIGN) {
+		if param.Rest {
+			p.errorAt(p.peekToken, CodeInvalidParameter, "rest parameter cannot have a default value")
+			return nil
+		}
+		p.nextToken()
+		p.nextToken()
+		param.Default = p.parseExpression(LOWEST)
+		if param.Default == nil {
+			return nil
+		}
 	}
-	return identifiers
+	return param
 }
 
 func (p *Parser) parseFunctionExpression() ast.Expression {
@@ -354,20 +1118,95 @@ func (p *Parser) parseFunctionExpression() ast.Expression {
 	if !p.expectedPeek(token.LBRACE) {
 		return nil
 	}
+	// 函数体中的break和continue不能跳出函数外面的循环
+	loops := p.loops
+	p.loops = 0
 	exp.Body = p.parseBlockStatement()
+	p.loops = loops
 	return exp
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	if dot, ok := function.(*ast.DotExpression); ok {
+		return p.parseMethodCallExpression(dot)
+	}
 	exp := &ast.CallExpression{
 		Token:     p.curToken,
 		Function:  function,
 		Arguments: nil,
 	}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseCallArguments()
+	if p.curTokenIs(token.RPAREN) {
+		exp.RParen = p.curToken
+	}
+	return exp
+}
+
+// parseMethodCallExpression 解析 obj.method(args)，dot是括号前面的 obj.method
+func (p *Parser) parseMethodCallExpression(dot *ast.DotExpression) ast.Expression {
+	exp := &ast.MethodCallExpression{
+		Token:    dot.Token,
+		Object:   dot.Left,
+		Method:   dot.Name,
+		Optional: dot.Optional,
+	}
+	exp.Arguments = p.parseCallArguments()
+	if p.curTokenIs(token.RPAREN) {
+		exp.RParen = p.curToken
+	}
 	return exp
 }
 
+// parseCallArguments 解析调用参数，...arr 把数组展开成多个参数，name: value 按参数名传递，
+// 按参数名传递的参数只能放在最后
+func (p *Parser) parseCallArguments() []ast.Expression {
+	var args []ast.Expression
+	open := p.curToken
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return args
+	}
+	named := make(map[string]bool)
+	for {
+		p.nextToken()
+		start := p.curToken
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			spread := &ast.SpreadExpression{Token: p.curToken}
+			p.nextToken()
+			spread.Value = p.parseExpression(LOWEST)
+			args = append(args, spread)
+		case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+			name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if named[name.Value] {
+				p.errorAt(start, CodeInvalidArgument, fmt.Sprintf("duplicate named argument %s", name.Value))
+				return nil
+			}
+			named[name.Value] = true
+			p.nextToken()
+			arg := &ast.NamedArgument{Token: p.curToken, Name: name}
+			p.nextToken()
+			arg.Value = p.parseExpression(LOWEST)
+			args = append(args, arg)
+		default:
+			args = append(args, p.parseExpression(LOWEST))
+		}
+		if _, ok := args[len(args)-1].(*ast.NamedArgument); !ok && len(named) > 0 {
+			p.errorAt(start, CodeInvalidArgument, "positional argument after named argument",
+				"pass named arguments after all positional arguments")
+			return nil
+		}
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectedClosing(token.RPAREN, open) {
+		return nil
+	}
+	return args
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{
 		Token: p.curToken,
@@ -375,8 +1214,48 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+// parseInterpolatedString 解析插值字符串，文本部分和 ${} 中的表达式交替出现
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	p.appendStringPart(str, p.curToken)
+	for {
+		// ${ 是当前Token的最后两个字符
+		open := p.curToken.End
+		open.Offset -= 2
+		open.Column -= 2
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+		switch {
+		case p.peekTokenIs(token.INTERP_MID):
+			p.nextToken()
+			p.appendStringPart(str, p.curToken)
+		case p.peekTokenIs(token.INTERP_END):
+			p.nextToken()
+			p.appendStringPart(str, p.curToken)
+			str.Close = p.curToken
+			return str
+		case p.peekTokenIs(token.ILLEGAL):
+			p.illegalTokenError(p.peekToken)
+			return nil
+		default:
+			p.errorAt(p.peekToken, CodeUnclosedDelimiter,
+				fmt.Sprintf("expected %s, but got %s", describeTokenType(token.RBRACE), describeToken(p.peekToken)),
+				fmt.Sprintf("to match \"${\" at %s", open))
+			return nil
+		}
+	}
+}
+
+// appendStringPart 把插值字符串中非空的文本部分加入到Parts中
+func (p *Parser) appendStringPart(str *ast.InterpolatedString, tk token.Token) {
+	if tk.Literal != "" {
+		str.Parts = append(str.Parts, &ast.StringLiteral{Token: tk, Value: tk.Literal})
+	}
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	var list []ast.Expression
+	open := p.curToken
 
 	if p.peekTokenIs(end) {
 		p.nextToken()
@@ -391,7 +1270,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
-	if !p.expectedPeek(end) {
+	if !p.expectedClosing(end, open) {
 		return nil
 	}
 	return list
@@ -402,19 +1281,96 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 		Elements: nil,
 	}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if p.curTokenIs(token.RBRACKET) {
+		array.RBracket = p.curToken
+	}
 	return array
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{
-		Token: p.curToken,
-		Left:  left,
-		Index: nil,
+		Token:    p.curToken,
+		Left:     left,
+		Index:    nil,
+		Optional: p.curTokenIs(token.OPTIONAL_LBRACKET),
+	}
+	if exp.Optional {
+		p.optionalIndex = &exp.Token
 	}
 	p.nextToken()
 	exp.Index = p.parseExpression(LOWEST)
-	if !p.expectedPeek(token.RBRACKET) {
+	if !p.expectedClosing(token.RBRACKET, exp.Token) {
+		return nil
+	}
+	exp.RBracket = p.curToken
+	return exp
+}
+
+// parseOptionalIndexOrConditional 解析 a?[i]。lexer把 c ?[1] : [2] 中的 ?[ 也当作可选索引，
+// ] 后面紧跟 : 时把它当作条件表达式 c ? [1] : [2]
+func (p *Parser) parseOptionalIndexOrConditional(left ast.Expression) ast.Expression {
+	open := p.curToken
+	p.optionalIndex = &open
+	elements := p.parseExpressionList(token.RBRACKET)
+	if !p.curTokenIs(token.RBRACKET) {
+		return nil
+	}
+	if p.peekTokenIs(token.COLON) {
+		question, bracket := splitOptionalBracket(open)
+		exp := &ast.ConditionalExpression{
+			Token:       question,
+			Condition:   left,
+			Consequence: &ast.ArrayLiteral{Token: bracket, Elements: elements, RBracket: p.curToken},
+		}
+		p.nextToken()
+		p.nextToken()
+		exp.Alternative = p.parseExpression(CONDITIONAL - 1)
+		if exp.Alternative == nil {
+			return nil
+		}
+		return exp
+	}
+	switch {
+	case len(elements) == 0:
+		p.errorAt(p.curToken, CodeExpectedExpression, fmt.Sprintf("expected an expression, but got %s", describeToken(p.curToken)))
 		return nil
+	case len(elements) > 1:
+		p.errorAt(open, CodeUnexpectedToken, fmt.Sprintf("expected one index inside %s, but got %d", describeTokenType(open.Type), len(elements)),
+			`write "? [" with a space to start a conditional expression`)
+		return nil
+	}
+	return &ast.IndexExpression{
+		Token:    open,
+		Left:     left,
+		Index:    elements[0],
+		RBracket: p.curToken,
+		Optional: true,
+	}
+}
+
+// splitOptionalBracket 把 ?[ 拆成 ? 和 [ 两个Token
+func splitOptionalBracket(tk token.Token) (question, bracket token.Token) {
+	middle := tk.Pos
+	middle.Offset++
+	middle.Column++
+	question = token.Token{Type: token.QUESTION, Literal: "?", Pos: tk.Pos, End: middle, Leading: tk.Leading}
+	bracket = token.Token{Type: token.LBRACKET, Literal: "[", Pos: middle, End: tk.End}
+	return question, bracket
+}
+
+// parseDotExpression 解析字段访问 h.k 或者 h?.k，点后面必须是标识符
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	exp := &ast.DotExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Optional: p.curTokenIs(token.OPTIONAL_DOT),
+	}
+	if !p.expectedPeek(token.IDENT, fmt.Sprintf("field names after %q must be identifiers", exp.Token.Literal)) {
+		return nil
+	}
+	exp.Name = &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
 	}
 	return exp
 }
@@ -426,6 +1382,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	}
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
+		p.colonFollows = true
 		key := p.parseExpression(LOWEST)
 		if !p.expectedPeek(token.COLON) {
 			return nil
@@ -433,33 +1390,38 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 		h.Pairs[key] = value
-		if !p.peekTokenIs(token.RBRACE) && !p.expectedPeek(token.COMMA) {
+		if !p.peekTokenIs(token.RBRACE) && !p.expectedPeek(token.COMMA, `separate pairs with "," or close the hash with "}"`) {
 			return nil
 		}
 	}
-	if !p.expectedPeek(token.RBRACE) {
+	if !p.expectedClosing(token.RBRACE, h.Token) {
 		return nil
 	}
+	h.RBrace = p.curToken
 	return h
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*Diagnostic{},
 	}
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 
 	p.registerPrefixFn(token.IDENT, p.parseIdentifier)
 	p.registerPrefixFn(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixFn(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefixFn(token.BAND, p.parsePrefixExpression)
 	p.registerPrefixFn(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefixFn(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefixFn(token.TRUE, p.parseBoolean)
 	p.registerPrefixFn(token.FALSE, p.parseBoolean)
 	p.registerPrefixFn(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefixFn(token.MATCH, p.parseMatchExpression)
 	p.registerPrefixFn(token.IF, p.parseIfExpression)
 	p.registerPrefixFn(token.FUNCTION, p.parseFunctionExpression)
 	p.registerPrefixFn(token.STRING, p.parseStringLiteral)
+	p.registerPrefixFn(token.INTERP_START, p.parseInterpolatedString)
 	p.registerPrefixFn(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefixFn(token.LBRACE, p.parseHashLiteral)
 
@@ -472,8 +1434,29 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixFn(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfixFn(token.LT, p.parseInfixExpression)
 	p.registerInfixFn(token.GT, p.parseInfixExpression)
+	p.registerInfixFn(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfixFn(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfixFn(token.PERCENT, p.parseInfixExpression)
+	p.registerInfixFn(token.POWER, p.parseInfixExpression)
+	p.registerInfixFn(token.AND, p.parseInfixExpression)
+	p.registerInfixFn(token.OR, p.parseInfixExpression)
+	p.registerInfixFn(token.NULLISH, p.parseInfixExpression)
+	p.registerInfixFn(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfixFn(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfixFn(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfixFn(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfixFn(token.SHL, p.parseInfixExpression)
+	p.registerInfixFn(token.SHR, p.parseInfixExpression)
 	p.registerInfixFn(token.LPAREN, p.parseCallExpression)
+	p.registerInfixFn(token.DOT, p.parseDotExpression)
+	p.registerInfixFn(token.OPTIONAL_DOT, p.parseDotExpression)
+	p.registerInfixFn(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfixFn(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfixFn(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfixFn(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfixFn(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfixFn(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixFn(token.OPTIONAL_LBRACKET, p.parseIndexExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -3,8 +3,10 @@ package parser
 import (
 	"BubblePL/ast"
 	"BubblePL/lexer"
+	"BubblePL/token"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +44,193 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	l := lexer.New("const limit = 10;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("got wrong type, expected=*ast.LetStatement, got=%T", program.Statements[0])
+	}
+	if stmt.Token.Type != token.CONST {
+		t.Errorf("wrong token type. expected=%s, got=%s", token.CONST, stmt.Token.Type)
+	}
+	if stmt.String() != "const limit = 10;" {
+		t.Errorf("wrong String(). got=%q", stmt.String())
+	}
+	testLiteralExpression(t, stmt.Value, 10)
+}
+
+func TestDotExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"h.k", "(h.k)"},
+		{"a.b.c", "((a.b).c)"},
+		{"-h.k", "(-(h.k))"},
+		{"h.k + 1", "((h.k) + 1)"},
+		{"h.f(1)", "h.f(1)"},
+		{"h.a[0]", "((h.a)[0])"},
+		{"a[0].k", "((a[0]).k)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a ? b : c", "(a ? b : c)"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a ? b ? c : d : e", "(a ? (b ? c : d) : e)"},
+		{"x > 0 && y ? x + 1 : -x", "(((x > 0) && y) ? (x + 1) : (-x))"},
+		{"x = a ? b : c", "x = (a ? b : c)"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a ?? b || c", "(a ?? (b || c))"},
+		{"a ?? b ? c : d", "((a ?? b) ? c : d)"},
+		{"f(a ? 1 : 2, b ?? 3)", "f((a ? 1 : 2), (b ?? 3))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"h?.k", "(h?.k)"},
+		{"a?[0]", "(a?[0])"},
+		{"h?.a.b", "((h?.a).b)"},
+		{"h.a?[0]?.k", "(((h.a)?[0])?.k)"},
+		{"h?.k ?? 1", "((h?.k) ?? 1)"},
+		{"c ? [1] : [2]", "(c ? [1] : [2])"},
+		// ?[ 后面的 ] 紧跟 : 时是条件表达式
+		{"c ?[1] : [2]", "(c ? [1] : [2])"},
+		{"c ?[1, 2] : []", "(c ? [1, 2] : [])"},
+		{"c ?[] : d ?[3] : [4]", "(c ? [] : (d ? [3] : [4]))"},
+		{"x = a.b ?[1] : 2", "x = ((a.b) ? [1] : 2)"},
+		{"f(c ?[1] : [2])", "f((c ? [1] : [2]))"},
+		{"{h?[0]: 1}", "{(h?[0]):1}"},
+		{"c ? h?[0] : 1", "(c ? (h?[0]) : 1)"},
+		{"c ? 1 : h?[0]", "(c ? 1 : (h?[0]))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestOptionalIndexConditionalHint(t *testing.T) {
+	tests := []string{
+		"a || c ?[1] : [2]",
+		"c ?[1][0] : 2",
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %v", input, len(errs), errs)
+		}
+		hint := `"?[" at 1:`
+		if len(errs[0].Hints) != 1 || !strings.Contains(errs[0].Hints[0], hint) || !strings.Contains(errs[0].Hints[0], `write "? [" with a space`) {
+			t.Errorf("%q: wrong hints. got=%q", input, errs[0].Hints)
+		}
+	}
+}
+
+func TestMethodCallExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"h.f()", "h.f()"},
+		{`"abc".upper()`, "abc.upper()"},
+		{"a.b.c(1, 2)", "(a.b).c(1, 2)"},
+		{"xs.map(f).filter(g)", "xs.map(f).filter(g)"},
+		{"h?.f(x: 1)", "h?.f(x: 1)"},
+		{"-x.abs() + 1", "((-x.abs()) + 1)"},
+		{"h.f()[0].k", "((h.f()[0]).k)"},
+		{`h["f"](1)`, "(h[f])(1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "x = 5"},
+		{"x = y = 1 + 2", "x = y = (1 + 2)"},
+		{"x += 1", "x += 1"},
+		{"x -= a * b", "x -= (a * b)"},
+		{"x *= 2", "x *= 2"},
+		{"x /= 2 == y", "x /= (2 == y)"},
+		{"x = a || b", "x = (a || b)"},
+		{"f(x = 1)", "f(x = 1)"},
+		{"a[0] = 1", "(a[0]) = 1"},
+		{"a[i + 1] += 2", "(a[(i + 1)]) += 2"},
+		{`h["k"] = v`, "(h[k]) = v"},
+		{"h.k = 1", "(h.k) = 1"},
+		{"h.a.b -= 1", "((h.a).b) -= 1"},
+		{"m[0].k = 1", "((m[0]).k) = 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, st ast.Statement, name string) bool {
 	if st.ToLiteral() != "let" {
 		t.Fatalf("Statement.ToLiteral error, expected=%s, got=%s", "let", st.ToLiteral())
@@ -67,8 +256,8 @@ func checkParseError(t *testing.T, p *Parser) {
 	if len(errs) == 0 {
 		return
 	}
-	for _, errMsg := range errs {
-		t.Errorf(errMsg)
+	for _, err := range errs {
+		t.Errorf(err.Error())
 	}
 	t.FailNow()
 }
@@ -165,6 +354,56 @@ func TestIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e-9", 1e-9},
+		{"2.5E3", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		fl, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if fl.Value != tt.expected {
+			t.Errorf("fl.Value not %g. got=%g", tt.expected, fl.Value)
+		}
+		if fl.String() != tt.input {
+			t.Errorf("fl.String() not %q. got=%q", tt.input, fl.String())
+		}
+	}
+}
+
+func TestBigIntegerExpression(t *testing.T) {
+	input := "99999999999999999999"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	il, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+	if il.Big == nil || il.Big.String() != input {
+		t.Errorf("il.Big wrong. got=%v", il.Big)
+	}
+	if il.String() != input {
+		t.Errorf("il.String() wrong. got=%s", il.String())
+	}
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	exp, ok := il.(*ast.IntegerLiteral)
 	if !ok {
@@ -191,6 +430,7 @@ func TestPrefixExpression(t *testing.T) {
 	}{
 		{"!5", "!", 5},
 		{"-15", "-", 15},
+		{"~15", "~", 15},
 		{"!foobar;", "!", "foobar"},
 		{"-foobar;", "-", "foobar"},
 		{"!true;", "!", true},
@@ -238,6 +478,17 @@ func TestInfixExpression(t *testing.T) {
 		{"5 < 5", 5, "<", 5},
 		{"5 == 5", 5, "==", 5},
 		{"5 != 5", 5, "!=", 5},
+		{"5 <= 5", 5, "<=", 5},
+		{"5 >= 5", 5, ">=", 5},
+		{"5 % 5", 5, "%", 5},
+		{"5 ** 5", 5, "**", 5},
+		{"5 & 5", 5, "&", 5},
+		{"5 | 5", 5, "|", 5},
+		{"5 ^ 5", 5, "^", 5},
+		{"5 << 5", 5, "<<", 5},
+		{"5 >> 5", 5, ">>", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -362,6 +613,66 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"2 ** -1",
+			"(2 ** (-1))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && c == d",
+			"((a < b) && (c == d))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"((a & b) == c)",
+		},
+		{
+			"1 << 2 + 3",
+			"(1 << (2 + 3))",
+		},
+		{
+			"a & 1 << b",
+			"(a & (1 << b))",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
+		{
+			"!a || b",
+			"((!a) || b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -541,6 +852,80 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < 1) { a } else if (x < 2) { b } else { c }`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("alternative should contain 1 statement. got=%d", len(exp.Alternative.Statements))
+	}
+	nested, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not *ast.IfExpression. got=%T", exp.Alternative.Statements[0])
+	}
+	testInfixExpression(t, nested.Condition, "x", "<", 2)
+	testIdentifier(t, nested.Alternative.Statements[0].(*ast.ExpressionStatement).Expression, "c")
+	if exp.End().Offset != len(input) || exp.Alternative.End().Offset != len(input) {
+		t.Errorf("wrong end. got=%s and %s", exp.End(), exp.Alternative.End())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 => a, _ => b }", "match (x) { 1 => a, _ => b }"},
+		{`match (x) { -1 => a, 1.5 => b, "s" => c, true => d }`, `match (x) { (-1) => a, 1.5 => b, "s" => c, true => d }`},
+		{"match (x) { [a, b] => a + b, [h, ...t] => t, [] => 0 }", "match (x) { [a, b] => (a + b), [h, ...t] => t, [] => 0 }"},
+		{`match (x) { {"k": [v, _], 1: w} => v }`, `match (x) { {"k": [v, _], 1: w} => v }`},
+		{"match (f(x)) { n if n > 0 => n, n => -n, }", "match (f(x)) { n if (n > 0) => n, n => (-n) }"},
+		{"match (x) { 1 => { let y = 2; y } _ => { 0 } }", "match (x) { 1 => let y = 2;y, _ => 0 }"},
+		{"match (x) { }", "match (x) {  }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b, ...rest] = arr;", "let [a, b, ...rest] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{`const {"pos": [x, y = 0], size = 1 + 1} = p;`, `const {"pos": [x, y = 0], size = (1 + 1)} = p;`},
+		{"let [[a, _], {b}] = f();", "let [[a, _], {b}] = f();"},
+		{"fn([a, b], {name, age = 1}) {}", "fn([a, b], {name, age = 1}) "},
+		{"fn(x, [a, b] = [1, 2]) {}", "fn(x, [a, b] = [1, 2]) "},
+		{"match (x) { {name, age = 0} => name }", "match (x) { {name, age = 0} => name }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestFunctionExpression(t *testing.T) {
 	input := `fn(x, y) {x + y;}`
 
@@ -564,8 +949,8 @@ func TestFunctionExpression(t *testing.T) {
 		t.Errorf("the numbers of parameters should be 2. got=%d", len(exp.Parameters))
 	}
 
-	testLiteralExpression(t, exp.Parameters[0], "x")
-	testLiteralExpression(t, exp.Parameters[1], "y")
+	testLiteralExpression(t, exp.Parameters[0].Name, "x")
+	testLiteralExpression(t, exp.Parameters[1].Name, "y")
 
 	if len(exp.Body.Statements) != 1 {
 		t.Errorf("the numbers of statements in exp.Body should be 1. got=%d", len(exp.Body.Statements))
@@ -604,7 +989,89 @@ func TestFunctionParameters(t *testing.T) {
 		}
 
 		for i, ident := range tt.expectedParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
+			testLiteralExpression(t, function.Parameters[i].Name, ident)
+		}
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, y = 10) {}", "fn(x, y = 10) "},
+		{"fn(x = 1 + 2, y = x * 2) {}", "fn(x = (1 + 2), y = (x * 2)) "},
+		{"fn(...rest) {}", "fn(...rest) "},
+		{"fn(x, y = 10, ...rest) {}", "fn(x, y = 10, ...rest) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionExpression)
+		if function.String() != tt.expected {
+			t.Errorf("wrong function. expected=%q, got=%q", tt.expected, function.String())
+		}
+		last := function.Parameters[len(function.Parameters)-1]
+		if last.Rest != strings.Contains(tt.input, "...") {
+			t.Errorf("%q: wrong Rest of last parameter. got=%t", tt.input, last.Rest)
+		}
+	}
+}
+
+func TestCallArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...arr)", "f(...arr)"},
+		{"f(1, ...[2, 3], ...rest(xs))", "f(1, ...[2, 3], ...rest(xs))"},
+		{"f(1, y: 2)", "f(1, y: 2)"},
+		{"f(x: 1 + 2, y: g(z: 3))", "f(x: (1 + 2), y: g(z: 3))"},
+		{"f(...arr, y: 2)", "f(...arr, y: 2)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x += 1 }", "while(x < 10) x += 1"},
+		{"while (true) { break; }", "whiletrue break;"},
+		{"for (let i = 0; i < n; i += 1) { continue; }", "for (let i = 0; (i < n); i += 1) continue;"},
+		{"for (i = 0; i < n; i += 1) { x }", "for (i = 0; (i < n); i += 1) x"},
+		{"for (;;) { break }", "for (; ; ) break;"},
+		{"for (; x;) { }; 1", "for (; x; ) 1"},
+		{"while (a) { while (b) { break } continue }", "whilea whileb break;continue;"},
+		{"for (x in xs) { x }", "for (x in xs) x"},
+		{"for (k, v in h) { k }", "for (k, v in h) k"},
+		{"for (i in range(0, n + 1)) { break }", "for (i in range(0, (n + 1))) break;"},
+		{"for (x in [1, 2]) { }; 1", "for (x in [1, 2]) 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
 		}
 	}
 }
@@ -670,6 +1137,36 @@ func TestStringLiteralExpression(t *testing.T) {
 
 }
 
+func TestInterpolatedStringExpression(t *testing.T) {
+	input := `"count: ${n + 1}!"`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+	if len(str.Parts) != 3 {
+		t.Fatalf("expected 3 parts, got=%d", len(str.Parts))
+	}
+	if text, ok := str.Parts[0].(*ast.StringLiteral); !ok || text.Value != "count: " {
+		t.Errorf("parts[0] wrong. got=%s", str.Parts[0])
+	}
+	testInfixExpression(t, str.Parts[1], "n", "+", 1)
+	if text, ok := str.Parts[2].(*ast.StringLiteral); !ok || text.Value != "!" {
+		t.Errorf("parts[2] wrong. got=%s", str.Parts[2])
+	}
+	if str.String() != `"count: ${(n + 1)}!"` {
+		t.Errorf("str.String() wrong. got=%s", str.String())
+	}
+	if str.End().Offset != len(input) {
+		t.Errorf("str.End() wrong. got=%d", str.End().Offset)
+	}
+}
+
 func TestParsingArrayLiteral(t *testing.T) {
 	input := "[1, 2*2, 3+3]"
 	l := lexer.New(input)
@@ -802,3 +1299,270 @@ func TestParsingHashLiteralsWithExpression(t *testing.T) {
 	}
 
 }
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedPos   string
+		expectedEnd   string
+		expectedStart int
+		expectedStop  int
+	}{
+		{"let x = 5;", "1:1", "1:10", 0, 9},
+		{"a + b * c", "1:1", "1:10", 0, 9},
+		{"-foo", "1:1", "1:5", 0, 4},
+		{"add(1,\n  2)", "1:1", "2:5", 0, 11},
+		{"[1, 2][0]", "1:1", "1:10", 0, 9},
+		{`{"a": 1}`, "1:1", "1:9", 0, 8},
+		{"fn(x) {\n  x\n}", "1:1", "3:2", 0, 13},
+		{"if (x) { 1 } else { 2 }", "1:1", "1:24", 0, 23},
+		{"return  x;", "1:1", "1:10", 0, 9},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		stmt := program.Statements[0]
+		if stmt.Pos().String() != tt.expectedPos {
+			t.Errorf("%q: wrong Pos. expected=%s, got=%s", tt.input, tt.expectedPos, stmt.Pos())
+		}
+		if stmt.End().String() != tt.expectedEnd {
+			t.Errorf("%q: wrong End. expected=%s, got=%s", tt.input, tt.expectedEnd, stmt.End())
+		}
+		if stmt.Pos().Offset != tt.expectedStart || stmt.End().Offset != tt.expectedStop {
+			t.Errorf("%q: wrong span. expected=[%d, %d), got=[%d, %d)", tt.input,
+				tt.expectedStart, tt.expectedStop, stmt.Pos().Offset, stmt.End().Offset)
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedCode    string
+		expectedMessage string
+		expectedPos     string
+	}{
+		{"let x 5;", CodeUnexpectedToken, `expected "=", but got integer "5"`, "1:7"},
+		{"let = 5;", CodeUnexpectedToken, `expected identifier, but got "="`, "1:5"},
+		{"let x = ;", CodeExpectedExpression, `expected an expression, but got ";"`, "1:9"},
+		{"add(1, 2", CodeUnclosedDelimiter, `expected ")", but got end of input`, "1:9"},
+		{"if (x { 1 }", CodeUnclosedDelimiter, `expected ")", but got "{"`, "1:7"},
+		{"fn(x) { x", CodeUnclosedDelimiter, `expected "}", but got end of input`, "1:10"},
+		{"fn(1) { 1 }", CodeUnexpectedToken, `expected identifier, but got integer "1"`, "1:4"},
+		{`{"a" 1}`, CodeUnexpectedToken, `expected ":", but got integer "1"`, "1:6"},
+		{"let x = @;", lexer.CodeIllegalCharacter, `illegal character "@"`, "1:9"},
+		{"let mask = 0b1021;", lexer.CodeMalformedNumber, `invalid digit '2' in binary literal`, "1:12"},
+		{"let n = 1__0;", lexer.CodeMalformedNumber, `'_' must separate successive digits`, "1:9"},
+		{"let h = 0x;", lexer.CodeMalformedNumber, `hexadecimal literal has no digits`, "1:9"},
+		{"let f = 2e;", lexer.CodeMalformedNumber, `exponent has no digits`, "1:9"},
+		{"let n = 010;", lexer.CodeMalformedNumber, `leading zeros are not allowed in decimal integer literals, use 0o for octal`, "1:9"},
+		{"let n = 089;", lexer.CodeMalformedNumber, `leading zeros are not allowed in decimal integer literals, use 0o for octal`, "1:9"},
+		{"1e999", CodeInvalidFloat, `could not parse "1e999" as float`, "1:1"},
+		{"let x = 1; /* never closed", lexer.CodeUnterminatedComment, "unterminated block comment", "1:12"},
+		{"let s = \"abc\nlet t = 1;", lexer.CodeUnterminatedString, "unterminated string literal", "1:9"},
+		{`"a ${1 2}"`, CodeUnclosedDelimiter, `expected "}", but got integer "2"`, "1:8"},
+		{`"a ${}"`, CodeExpectedExpression, `expected an expression, but got "}"`, "1:6"},
+		{`let s = "\q";`, lexer.CodeInvalidEscape, `invalid escape sequence "\\q"`, "1:10"},
+		{"fn(...rest, x) { x }", CodeInvalidParameter, "parameter after rest parameter", "1:13"},
+		{"fn(x = 1, y) { y }", CodeInvalidParameter, "parameter y without default value follows a parameter with default value", "1:11"},
+		{"fn(x, x) { x }", CodeInvalidParameter, "duplicate parameter x", "1:7"},
+		{"fn(...rest = []) { rest }", CodeInvalidParameter, "rest parameter cannot have a default value", "1:12"},
+		{"f(x: 1, 2)", CodeInvalidArgument, "positional argument after named argument", "1:9"},
+		{"f(x: 1, ...xs)", CodeInvalidArgument, "positional argument after named argument", "1:9"},
+		{"f(x: 1, x: 2)", CodeInvalidArgument, "duplicate named argument x", "1:9"},
+		{"1 = 2", CodeInvalidAssignment, "cannot assign to 1", "1:1"},
+		{"a + b += 1", CodeInvalidAssignment, "cannot assign to (a + b)", "1:1"},
+		{"f() = 1", CodeInvalidAssignment, "cannot assign to f()", "1:1"},
+		{"h.1", CodeUnexpectedToken, `expected identifier, but got integer "1"`, "1:3"},
+		{"break;", CodeOutsideLoop, "break outside of a loop", "1:1"},
+		{"if (x) { continue }", CodeOutsideLoop, "continue outside of a loop", "1:10"},
+		{"while (x) { fn() { break } }", CodeOutsideLoop, "break outside of a loop", "1:20"},
+		{"while x { 1 }", CodeUnexpectedToken, `expected "(", but got identifier "x"`, "1:7"},
+		{"for (let i = 0 i < 1) { 1 }", CodeUnexpectedToken, `expected ";", but got identifier "i"`, "1:16"},
+		{"for (;; x { 1 }", CodeUnclosedDelimiter, `expected ")", but got "{"`, "1:11"},
+		{"for (k, 1 in h) { 1 }", CodeUnexpectedToken, `expected identifier, but got integer "1"`, "1:9"},
+		{"for (k, v of h) { 1 }", CodeUnexpectedToken, `expected "in", but got identifier "of"`, "1:11"},
+		{"for (x in xs { 1 }", CodeUnclosedDelimiter, `expected ")", but got "{"`, "1:14"},
+		{"if (x) { 1 } else 2", CodeUnexpectedToken, `expected "{", but got integer "2"`, "1:19"},
+		{"match x { _ => 1 }", CodeUnexpectedToken, `expected "(", but got identifier "x"`, "1:7"},
+		{"match (x) { 1 + 2 => 1 }", CodeUnexpectedToken, `expected "=>", but got "+"`, "1:15"},
+		{"match (x) { 1 => 1 2 => 2 }", CodeUnexpectedToken, `expected ",", but got integer "2"`, "1:20"},
+		{"match (x) { fn => 1 }", CodeInvalidPattern, `expected a pattern, but got "fn"`, "1:13"},
+		{"match (x) { [a, ...r, b] => 1 }", CodeInvalidPattern, "elements after the rest of an array pattern", "1:21"},
+		{"match (x) { {k: 1} => 1 }", CodeInvalidPattern, `expected a string, integer or boolean key, but got identifier "k"`, "1:14"},
+		{"match (x) { 1 => 1", CodeUnclosedDelimiter, `expected "}", but got end of input`, "1:19"},
+		{"match (x) { [a, a] => a }", CodeInvalidPattern, "a is bound more than once", "1:17"},
+		{"match (x) { [a, ...b] => 1, {\"k\": [b], b} => b }", CodeInvalidPattern, "b is bound more than once", "1:40"},
+		{"const = 1;", CodeUnexpectedToken, `expected identifier, but got "="`, "1:7"},
+		{"let [a, a] = x;", CodeInvalidPattern, "a is bound more than once", "1:9"},
+		{"let [a, {b, a}] = x;", CodeInvalidPattern, "a is bound more than once", "1:13"},
+		{"let [a, [b] = [1]] = x;", CodeInvalidPattern, "[b] cannot have a default value", "1:13"},
+		{"let {k: v} = x;", CodeInvalidPattern, `expected a string, integer or boolean key, but got identifier "k"`, "1:6"},
+		{"let [a, b];", CodeUnexpectedToken, `expected "=", but got ";"`, "1:11"},
+		{"fn(x, [x]) { x }", CodeInvalidPattern, "x is bound more than once", "1:8"},
+		{"fn([a], a) { a }", CodeInvalidParameter, "duplicate parameter a", "1:9"},
+		{"fn(...[a]) { a }", CodeUnexpectedToken, `expected identifier, but got "["`, "1:7"},
+		{"a ? b", CodeUnexpectedToken, `expected ":", but got end of input`, "1:6"},
+		{"a ? b : ", CodeExpectedExpression, "expected an expression, but got end of input", "1:9"},
+		{"h?.1", CodeUnexpectedToken, `expected identifier, but got integer "1"`, "1:4"},
+		{"h?.k = 1", CodeInvalidAssignment, "cannot assign to optional chain (h?.k)", "1:1"},
+		{"h?.a.b = 1", CodeInvalidAssignment, "cannot assign to optional chain ((h?.a).b)", "1:1"},
+		{"a?[0] += 1", CodeInvalidAssignment, "cannot assign to optional chain (a?[0])", "1:1"},
+		{"a?[]", CodeExpectedExpression, `expected an expression, but got "]"`, "1:4"},
+		{"a?[1, 2]", CodeUnexpectedToken, `expected one index inside "?[", but got 2`, "1:2"},
+		{"a || c ?[1] : [2]", CodeExpectedExpression, `expected an expression, but got ":"`, "1:13"},
+		{"h.f() = 1", CodeInvalidAssignment, "cannot assign to h.f()", "1:1"},
+		{"h?.f().k = 1", CodeInvalidAssignment, "cannot assign to optional chain (h?.f().k)", "1:1"},
+		{"h.f(1", CodeUnclosedDelimiter, `expected ")", but got end of input`, "1:6"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) != 1 {
+			t.Errorf("%q: expected 1 error, got=%d %v", tt.input, len(errs), errs)
+			continue
+		}
+		if errs[0].Severity != SeverityError {
+			t.Errorf("%q: wrong severity. got=%s", tt.input, errs[0].Severity)
+		}
+		if errs[0].Code != tt.expectedCode {
+			t.Errorf("%q: wrong code. expected=%s, got=%s", tt.input, tt.expectedCode, errs[0].Code)
+		}
+		if errs[0].Message != tt.expectedMessage {
+			t.Errorf("%q: wrong message. expected=%q, got=%q", tt.input, tt.expectedMessage, errs[0].Message)
+		}
+		if errs[0].Pos.String() != tt.expectedPos {
+			t.Errorf("%q: wrong position. expected=%s, got=%s", tt.input, tt.expectedPos, errs[0].Pos)
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     int
+		expectedStatements []string
+	}{
+		// 缺少分号也能正常解析
+		{"let x = 5", 0, []string{"let x = 5;"}},
+		{"return x", 0, []string{"return x;"}},
+		// 一条语句出错只报告一个错误，后面的语句继续解析
+		{"let x 5 * 3 + 2; let y = 2;", 1, []string{"let y = 2;"}},
+		{"let x 5 * 3 + 2\nlet y = 2;", 1, []string{"let y = 2;"}},
+		{"let x = (1 + ; let y = 2; y", 1, []string{"let y = 2;", "y"}},
+		{"let f = fn() { let a 1; a }; f()", 1, []string{"let f = fn() a;", "f()"}},
+		{"let x = ; let y = ; z", 2, []string{"z"}},
+		// 参数列表或者条件中的错误跳过后面整个块，不会在块中的语句处停下
+		{"let f = fn(x, x) { let y = 1; y }; z", 1, []string{"z"}},
+		{"let f = fn(x, 1) {\n let a = 1;\n};\nz", 1, []string{"z"}},
+		{"if (1 +) { let a = 1; a }\nlet z = 1;", 1, []string{"let z = 1;"}},
+		{"let f = fn() { if (1 +) { let a = 1; a }; let b = 2; b }; f()", 1, []string{"let f = fn() let b = 2;b;", "f()"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != tt.expectedErrors {
+			t.Errorf("%q: expected %d errors, got=%d %v", tt.input, tt.expectedErrors, len(p.Errors()), p.Errors())
+		}
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Errorf("%q: expected %d statements, got=%d", tt.input, len(tt.expectedStatements), len(program.Statements))
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expectedStatements[i] {
+				t.Errorf("%q: statements[%d] expected=%q, got=%q", tt.input, i, tt.expectedStatements[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestDiagnosticFormat(t *testing.T) {
+	input := "let x = 1;\nlet y 2;"
+	p := New(lexer.New(input))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got=%d", len(p.Errors()))
+	}
+	expected := "2:7: error[P0001]: expected \"=\", but got integer \"2\"\n" +
+		"    let y 2;\n" +
+		"          ^\n" +
+		"    hint: let statements have the form: let <name> = <expression>;\n"
+	if got := p.Errors()[0].Format(input); got != expected {
+		t.Errorf("wrong format. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestDiagnosticFormatUnicode(t *testing.T) {
+	input := "let 名字 = \"你好\" +;"
+	p := New(lexer.New(input))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got=%d", len(p.Errors()))
+	}
+	expected := "1:16: error[P0002]: expected an expression, but got \";\"\n" +
+		"    let 名字 = \"你好\" +;\n" +
+		"                   ^\n"
+	if got := p.Errors()[0].Format(input); got != expected {
+		t.Errorf("wrong format. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 1; # trailing
+let f = fn() {
+	/* inside */ x
+	// end of block
+};
+// end of file`
+	l := lexer.New(input)
+	l.KeepComments(true)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got=%d", len(program.Statements))
+	}
+	block := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionExpression).Body
+
+	tests := []struct {
+		node     ast.Node
+		expected []string
+	}{
+		{program.Statements[0], []string{"// leading"}},
+		{program.Statements[1], []string{"# trailing"}},
+		{block.Statements[0], []string{"/* inside */"}},
+		{block, []string{"// end of block"}},
+		{program, []string{"// end of file"}},
+	}
+	for i, tt := range tests {
+		comments := program.Comments[tt.node]
+		if len(comments) != len(tt.expected) {
+			t.Errorf("tests[%d]: expected %d comments, got=%d", i, len(tt.expected), len(comments))
+			continue
+		}
+		for j, comment := range comments {
+			if comment.Text != tt.expected[j] {
+				t.Errorf("tests[%d]: comment[%d] expected=%q, got=%q", i, j, tt.expected[j], comment.Text)
+			}
+		}
+	}
+
+	// 默认不保留注释
+	program = New(lexer.New(input)).ParseProgram()
+	if program.Comments != nil {
+		t.Errorf("expected no comments, got=%v", program.Comments)
+	}
+}
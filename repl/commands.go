@@ -0,0 +1,188 @@
+package repl
+
+import (
+	"BubblePL/ast"
+	"BubblePL/evaluator"
+	"BubblePL/lexer"
+	"BubblePL/object"
+	"BubblePL/parser"
+	"BubblePL/token"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// session 一次REPL会话，保存会话中的环境
+type session struct {
+	env     *object.Environment
+	out     io.Writer
+	checked bool // 整数运算溢出时是否报错，:reset 之后仍然有效
+}
+
+func newSession(out io.Writer, checked bool) *session {
+	s := &session{out: out, checked: checked}
+	s.env = s.newEnvironment()
+	return s
+}
+
+// newEnvironment 创建会话使用的全局环境
+func (s *session) newEnvironment() *object.Environment {
+	env := object.NewEnvironment()
+	env.SetCheckedArithmetic(s.checked)
+	return env
+}
+
+// command 以冒号开头的REPL命令
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, arg string) bool // 返回false时结束REPL
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"tokens": {":tokens <code>", "print the tokens produced by the lexer", (*session).tokensCommand},
+		"ast":    {":ast <code>", "print the parsed program and its syntax tree", (*session).astCommand},
+		"env":    {":env", "list the bindings in the current environment", (*session).envCommand},
+		"type":   {":type <expr>", "evaluate an expression and print its type", (*session).typeCommand},
+		"load":   {":load <file>", "evaluate a file into the current environment", (*session).loadCommand},
+		"reset":  {":reset", "start over with a fresh environment", (*session).resetCommand},
+		"help":   {":help", "show this help", (*session).helpCommand},
+		"quit":   {":quit", "exit the REPL", (*session).quitCommand},
+	}
+}
+
+// runCommand 执行一行以冒号开头的命令，返回false时结束REPL
+func (s *session) runCommand(line string) bool {
+	name, arg, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, ":")), " ")
+	arg = strings.TrimSpace(arg)
+	cmd, ok := commands[name]
+	if !ok && name == "q" {
+		cmd, ok = commands["quit"]
+	}
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command :%s, type :help for the list of commands\n", name)
+		return true
+	}
+	return cmd.run(s, arg)
+}
+
+// eval 解析并执行源代码，输出解析错误、运行时错误或者执行结果
+func (s *session) eval(filename, source string) {
+	program, ok := s.parse(filename, source)
+	if !ok {
+		return
+	}
+	evaluated := evaluator.Eval(program, s.env)
+	if err, ok := evaluated.(*object.Error); ok {
+		io.WriteString(s.out, err.StackTrace())
+		io.WriteString(s.out, "\n")
+	} else if evaluated != nil {
+		io.WriteString(s.out, evaluated.Inspect())
+		io.WriteString(s.out, "\n")
+	}
+}
+
+// parse 解析源代码，有错误时输出错误并返回false
+func (s *session) parse(filename, source string) (*ast.Program, bool) {
+	l := lexer.NewWithFilename(filename, source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(s.out, source, p.Errors())
+		return nil, false
+	}
+	return program, true
+}
+
+func (s *session) tokensCommand(arg string) bool {
+	l := lexer.New(arg)
+	for {
+		tk := l.NextToken()
+		fmt.Fprintf(s.out, "%-6s %-10s %q\n", tk.Pos, tk.Type, tk.Literal)
+		if tk.Type == token.EOF {
+			return true
+		}
+	}
+}
+
+func (s *session) astCommand(arg string) bool {
+	program, ok := s.parse("", arg)
+	if !ok {
+		return true
+	}
+	io.WriteString(s.out, program.String()+"\n")
+	io.WriteString(s.out, ast.Dump(program))
+	return true
+}
+
+func (s *session) envCommand(string) bool {
+	for _, name := range s.env.Names() {
+		value, _ := s.env.Get(name)
+		fmt.Fprintf(s.out, "%s: %s = %s\n", name, value.Type(), summarize(value))
+	}
+	return true
+}
+
+func (s *session) typeCommand(arg string) bool {
+	program, ok := s.parse("", arg)
+	if !ok {
+		return true
+	}
+	evaluated := evaluator.Eval(program, s.env)
+	switch {
+	case evaluated == nil:
+		io.WriteString(s.out, "no value\n")
+	case evaluated.Type() == object.ERROR_OBJ:
+		io.WriteString(s.out, evaluated.(*object.Error).StackTrace()+"\n")
+	default:
+		io.WriteString(s.out, string(evaluated.Type())+"\n")
+	}
+	return true
+}
+
+func (s *session) loadCommand(arg string) bool {
+	if arg == "" {
+		io.WriteString(s.out, "usage: "+commands["load"].usage+"\n")
+		return true
+	}
+	source, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "%s\n", err)
+		return true
+	}
+	s.eval(arg, string(source))
+	return true
+}
+
+func (s *session) resetCommand(string) bool {
+	s.env = s.newEnvironment()
+	io.WriteString(s.out, "environment reset\n")
+	return true
+}
+
+func (s *session) helpCommand(string) bool {
+	for _, name := range []string{"tokens", "ast", "env", "type", "load", "reset", "help", "quit"} {
+		fmt.Fprintf(s.out, "  %-16s %s\n", commands[name].usage, commands[name].help)
+	}
+	return true
+}
+
+func (s *session) quitCommand(string) bool {
+	return false
+}
+
+// summarize 返回值的单行描述，函数只显示参数列表
+func summarize(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		params := make([]string, 0, len(fn.Parameters))
+		for _, p := range fn.Parameters {
+			params = append(params, p.String())
+		}
+		return "fn(" + strings.Join(params, ", ") + ") {...}"
+	}
+	return strings.ReplaceAll(obj.Inspect(), "\n", " ")
+}
@@ -0,0 +1,349 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupt 用户按下Ctrl-C放弃了当前的输入
+var errInterrupt = errors.New("interrupt")
+
+// lineReader 读取用户输入的一行
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// plainReader 从非终端的输入中按行读取，例如管道和文件
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// 终端按键
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// 转义序列解析后的按键，使用Unicode私有区的字符避免和输入冲突
+const (
+	keyUp rune = 0xE000 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// lineEditor 在原始模式的终端上编辑一行输入，支持光标移动、上下键浏览历史记录和Ctrl-R反向搜索
+type lineEditor struct {
+	fd      int
+	in      *bufio.Reader
+	out     io.Writer
+	history *history
+}
+
+// editState 一次ReadLine的编辑状态
+type editState struct {
+	prompt     string
+	buf        []rune
+	pos        int    // 光标在buf中的位置
+	historyIdx int    // 正在浏览的历史记录，等于历史记录条数时表示正在编辑新的输入
+	draft      []rune // 浏览历史记录之前正在编辑的输入
+}
+
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	s := &editState{prompt: prompt, historyIdx: len(e.history.entries)}
+	e.refresh(s)
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case keyEnter, '\n':
+			s.pos = len(s.buf)
+			e.refresh(s)
+			io.WriteString(e.out, "\r\n")
+			return string(s.buf), nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupt
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.delete()
+		case keyBackspace, keyCtrlH:
+			if s.pos > 0 {
+				s.pos--
+				s.delete()
+			}
+		case keyDelete:
+			s.delete()
+		case keyLeft, keyCtrlB:
+			if s.pos > 0 {
+				s.pos--
+			}
+		case keyRight, keyCtrlF:
+			if s.pos < len(s.buf) {
+				s.pos++
+			}
+		case keyHome, keyCtrlA:
+			s.pos = 0
+		case keyEnd, keyCtrlE:
+			s.pos = len(s.buf)
+		case keyCtrlK:
+			s.buf = s.buf[:s.pos]
+		case keyCtrlU:
+			s.buf = append([]rune{}, s.buf[s.pos:]...)
+			s.pos = 0
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP:
+			e.historyMove(s, -1)
+		case keyDown, keyCtrlN:
+			e.historyMove(s, 1)
+		case keyCtrlR:
+			done, err := e.reverseSearch(s)
+			if err != nil {
+				return "", err
+			}
+			if done {
+				io.WriteString(e.out, "\r\n")
+				return string(s.buf), nil
+			}
+		default:
+			if unicode.IsPrint(key) {
+				s.insert(key)
+			}
+		}
+		e.refresh(s)
+	}
+}
+
+func (s *editState) insert(r rune) {
+	s.buf = append(s.buf, 0)
+	copy(s.buf[s.pos+1:], s.buf[s.pos:])
+	s.buf[s.pos] = r
+	s.pos++
+}
+
+// delete 删除光标处的字符
+func (s *editState) delete() {
+	if s.pos < len(s.buf) {
+		s.buf = append(s.buf[:s.pos], s.buf[s.pos+1:]...)
+	}
+}
+
+// historyMove 向前(-1)或者向后(1)浏览历史记录
+func (e *lineEditor) historyMove(s *editState, direction int) {
+	idx := s.historyIdx + direction
+	if idx < 0 || idx > len(e.history.entries) {
+		return
+	}
+	if s.historyIdx == len(e.history.entries) {
+		s.draft = s.buf
+	}
+	s.historyIdx = idx
+	if idx == len(e.history.entries) {
+		s.buf = s.draft
+	} else {
+		s.buf = []rune(e.history.entries[idx])
+	}
+	s.pos = len(s.buf)
+}
+
+// reverseSearch Ctrl-R反向搜索历史记录，再次按Ctrl-R查找更早的记录。
+// 回车直接提交找到的记录(返回true)，其他编辑键接受找到的记录继续编辑，Ctrl-G或者Ctrl-C取消搜索
+func (e *lineEditor) reverseSearch(s *editState) (bool, error) {
+	original, originalPos := s.buf, s.pos
+	var query []rune
+	matchIdx := len(e.history.entries)
+
+	search := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(e.history.entries[i], string(query)) {
+				matchIdx = i
+				s.buf = []rune(e.history.entries[i])
+				s.pos = len(s.buf)
+				return
+			}
+		}
+	}
+
+	for {
+		prompt := fmt.Sprintf("(reverse-i-search)`%s': ", string(query))
+		if matchIdx == len(e.history.entries) && len(query) > 0 {
+			prompt = "(failing " + prompt[1:]
+		}
+		e.render(prompt, s.buf, len(s.buf))
+
+		key, err := e.readKey()
+		if err != nil {
+			return false, err
+		}
+		switch key {
+		case keyCtrlR:
+			if len(query) > 0 && matchIdx > 0 {
+				search(matchIdx - 1)
+			}
+		case keyBackspace, keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				matchIdx = len(e.history.entries)
+				s.buf, s.pos = original, originalPos
+				if len(query) > 0 {
+					search(len(e.history.entries) - 1)
+				}
+			}
+		case keyCtrlG, keyCtrlC:
+			s.buf, s.pos = original, originalPos
+			return false, nil
+		case keyEnter, '\n':
+			e.render(s.prompt, s.buf, len(s.buf))
+			return true, nil
+		default:
+			if unicode.IsPrint(key) {
+				query = append(query, key)
+				from := matchIdx
+				if from == len(e.history.entries) {
+					from--
+				}
+				matchIdx = len(e.history.entries)
+				search(from)
+				continue
+			}
+			// 其他按键结束搜索，保留找到的记录继续编辑
+			s.historyIdx = len(e.history.entries)
+			return false, nil
+		}
+	}
+}
+
+// readKey 读取一个按键，把方向键等转义序列转换成对应的按键
+func (e *lineEditor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	// 读取 ESC [ 参数 结束符
+	var params []rune
+	for {
+		c, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if c >= '0' && c <= '9' || c == ';' {
+			params = append(params, c)
+			continue
+		}
+		switch c {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		case 'H':
+			return keyHome, nil
+		case 'F':
+			return keyEnd, nil
+		case '~':
+			switch string(params) {
+			case "1", "7":
+				return keyHome, nil
+			case "4", "8":
+				return keyEnd, nil
+			case "3":
+				return keyDelete, nil
+			}
+		}
+		return keyUnknown, nil
+	}
+}
+
+func (e *lineEditor) refresh(s *editState) {
+	e.render(s.prompt, s.buf, s.pos)
+}
+
+// render 重新绘制当前行并把光标移动到pos处，多行的输入中的换行显示为 ↵
+func (e *lineEditor) render(prompt string, buf []rune, pos int) {
+	var out strings.Builder
+	out.WriteString("\r")
+	out.WriteString(prompt)
+	out.WriteString(strings.ReplaceAll(string(buf), "\n", "↵"))
+	out.WriteString("\x1b[K\r")
+	if width := displayWidth([]rune(prompt)) + displayWidth(buf[:pos]); width > 0 {
+		fmt.Fprintf(&out, "\x1b[%dC", width)
+	}
+	io.WriteString(e.out, out.String())
+}
+
+// displayWidth 字符在终端中占用的列数，中日韩文字和emoji占两列
+func displayWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		switch {
+		case unicode.Is(unicode.Mn, r) || r == 0xFE0F:
+			// 组合字符不占用宽度
+		case r >= 0x1100 && r <= 0x115F, r >= 0x2E80 && r <= 0xA4CF,
+			r >= 0xAC00 && r <= 0xD7A3, r >= 0xF900 && r <= 0xFAFF,
+			r >= 0xFE30 && r <= 0xFE4F, r >= 0xFF00 && r <= 0xFF60,
+			r >= 0xFFE0 && r <= 0xFFE6, r >= 0x1F300 && r <= 0x1FAFF,
+			r >= 0x20000 && r <= 0x3FFFD:
+			width += 2
+		default:
+			width += 1
+		}
+	}
+	return width
+}
@@ -0,0 +1,138 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HISTORY_FILE 用户主目录下保存REPL历史记录的文件，可以通过环境变量 BUBBLE_HISTORY 修改
+const HISTORY_FILE = ".bubble_history"
+
+// maxHistory 最多保留的历史记录条数
+const maxHistory = 1000
+
+// history REPL的历史记录，每一条记录是一次完整的输入，可能包含多行
+type history struct {
+	entries []string
+	path    string // 历史记录文件，为空时不保存
+	lines   int    // 历史记录文件中的记录条数，超过maxHistory的两倍时重写文件
+}
+
+// historyPath 返回历史记录文件的路径
+func historyPath() string {
+	if path := os.Getenv("BUBBLE_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// loadHistory 从文件中读取历史记录，文件不存在时返回空的历史记录
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, decodeHistoryEntry(line))
+		}
+	}
+	h.lines = len(h.entries)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		h.compact()
+	}
+	return h
+}
+
+// add 添加一条历史记录并追加到历史记录文件中，和上一条相同的记录会被忽略
+func (h *history) add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+	if h.path == "" {
+		return
+	}
+	// 每次都重写文件太慢，追加的记录累积到maxHistory条时再重写
+	if h.lines >= 2*maxHistory {
+		h.compact()
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(encodeHistoryEntry(entry) + "\n"); err == nil {
+		h.lines++
+	}
+}
+
+// compact 用内存中最近的maxHistory条记录重写历史记录文件。先写到同一目录的临时文件再重命名，
+// 写入失败时原来的文件不受影响
+func (h *history) compact() {
+	file, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err != nil {
+		return
+	}
+	writer := bufio.NewWriter(file)
+	for _, entry := range h.entries {
+		writer.WriteString(encodeHistoryEntry(entry) + "\n")
+	}
+	err = writer.Flush()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), h.path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return
+	}
+	h.lines = len(h.entries)
+}
+
+// encodeHistoryEntry 把多行的记录编码成一行，\ 写成 \\，换行写成 \n
+func encodeHistoryEntry(entry string) string {
+	entry = strings.ReplaceAll(entry, `\`, `\\`)
+	return strings.ReplaceAll(entry, "\n", `\n`)
+}
+
+// decodeHistoryEntry 还原encodeHistoryEntry编码的记录
+func decodeHistoryEntry(line string) string {
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				out.WriteByte('\n')
+			} else {
+				out.WriteByte(line[i])
+			}
+			continue
+		}
+		out.WriteByte(line[i])
+	}
+	return out.String()
+}
@@ -0,0 +1,29 @@
+package repl
+
+import (
+	"BubblePL/lexer"
+	"BubblePL/token"
+	"strings"
+)
+
+// isIncomplete 判断输入是否还没有结束：存在没有闭合的括号、反引号字符串或者块注释
+func isIncomplete(source string) bool {
+	l := lexer.New(source)
+	depth := 0
+	for {
+		tk := l.NextToken()
+		switch tk.Type {
+		case token.EOF:
+			return depth > 0
+		case token.LPAREN, token.LBRACKET, token.OPTIONAL_LBRACKET, token.LBRACE, token.INTERP_START:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE, token.INTERP_END:
+			depth--
+		case token.ILLEGAL:
+			// 一直延续到输入结尾的反引号字符串和块注释还可以在下一行结束
+			if tk.End.Offset == len(source) && (strings.HasPrefix(tk.Literal, "`") || strings.HasPrefix(tk.Literal, "/*")) {
+				return true
+			}
+		}
+	}
+}
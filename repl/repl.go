@@ -1,43 +1,78 @@
 package repl
 
 import (
-	"BubblePL/evaluator"
-	"BubblePL/lexer"
-	"BubblePL/object"
 	"BubblePL/parser"
 	"bufio"
-	"fmt"
 	"io"
+	"os"
+	"strings"
 )
 
 const PROMPT = "🫧>> "
 
-func printParseErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// CONTINUATION_PROMPT 输入还没有结束时显示的提示符
+const CONTINUATION_PROMPT = "🫧.. "
+
+func printParseErrors(out io.Writer, source string, errors []*parser.Diagnostic) {
+	for _, diagnostic := range errors {
+		io.WriteString(out, diagnostic.Format(source))
 	}
 }
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+
+// newLineReader 输入输出都是终端时使用支持历史记录的行编辑器，否则按行读取
+func newLineReader(in io.Reader, out io.Writer) lineReader {
+	inFile, inOk := in.(*os.File)
+	outFile, outOk := out.(*os.File)
+	if inOk && outOk && isTerminal(int(inFile.Fd())) && isTerminal(int(outFile.Fd())) {
+		return &lineEditor{
+			fd:      int(inFile.Fd()),
+			in:      bufio.NewReader(inFile),
+			out:     out,
+			history: loadHistory(historyPath()),
+		}
+	}
+	return &plainReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// Start 运行REPL直到输入结束，checked 为true时整数运算溢出会报错
+func Start(in io.Reader, out io.Writer, checked bool) {
+	reader := newLineReader(in, out)
+	s := newSession(out, checked)
+	var pending []string
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		prompt := PROMPT
+		if len(pending) > 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+		line, err := reader.ReadLine(prompt)
+		if err == errInterrupt {
+			pending = nil
+			continue
+		}
+		if err != nil {
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+		if len(pending) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if editor, ok := reader.(*lineEditor); ok {
+				editor.history.add(line)
+			}
+			if !s.runCommand(line) {
+				return
+			}
+			continue
+		}
+		pending = append(pending, line)
+		source := strings.Join(pending, "\n")
+		if isIncomplete(source) {
+			continue
+		}
+		pending = nil
+		if strings.TrimSpace(source) == "" {
 			continue
 		}
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		if editor, ok := reader.(*lineEditor); ok {
+			editor.history.add(source)
 		}
+		s.eval("", source)
 	}
 }
@@ -0,0 +1,165 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let x = 5;", false},
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) {\n  a + b\n}", false},
+		{"add(1,", true},
+		{"[1, 2,\n3", true},
+		{"let f = fn() { a?[0]", true},
+		{"let f = fn() { a?[0] }", false},
+		{"a?[", true},
+		{`let s = "hello`, false},
+		{"let s = `hello", true},
+		{"let s = `hello\n{", true},
+		{"let s = `hello\n{`", false},
+		{`let s = "\"{"`, false},
+		{`let s = "${ add(1,`, true},
+		{`let s = "${ {"a": 1}["a"] }"`, false},
+		{`let s = "hello {"`, false},
+		{"}", false},
+		{"let x = 1; // {", false},
+		{"# (\nlet x = 1;", false},
+		{"/* outer /* inner */", true},
+		{"/* outer /* inner */ */ let x = 1;", false},
+		{"/* { */", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIncomplete(tt.input); got != tt.expected {
+			t.Errorf("isIncomplete(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestHistoryEntryEncoding(t *testing.T) {
+	entries := []string{
+		"let x = 5;",
+		"let f = fn(x) {\n  x\n}",
+		`let s = "a\nb";`,
+		`\`,
+	}
+
+	for _, entry := range entries {
+		encoded := encodeHistoryEntry(entry)
+		for _, ch := range encoded {
+			if ch == '\n' {
+				t.Errorf("encoded entry contains newline: %q", encoded)
+			}
+		}
+		if decoded := decodeHistoryEntry(encoded); decoded != entry {
+			t.Errorf("history entry round trip failed. expected=%q, got=%q", entry, decoded)
+		}
+	}
+}
+
+func TestHistoryFileLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var lines []string
+	for i := 0; i < maxHistory+10; i++ {
+		lines = append(lines, fmt.Sprintf("let a = %d;", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	countLines := func() int {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	// 读取时超过maxHistory条的文件会被重写
+	h := loadHistory(path)
+	if len(h.entries) != maxHistory || h.entries[0] != "let a = 10;" {
+		t.Fatalf("wrong entries after load. len=%d, first=%q", len(h.entries), h.entries[0])
+	}
+	if n := countLines(); n != maxHistory {
+		t.Errorf("history file not compacted on load. expected=%d lines, got=%d", maxHistory, n)
+	}
+
+	// 追加的记录累积到一定数量时也会重写文件
+	for i := 0; i < 3*maxHistory; i++ {
+		h.add(fmt.Sprintf("let b = %d;", i))
+		if n := countLines(); n > 2*maxHistory {
+			t.Fatalf("history file grew to %d lines", n)
+		}
+	}
+	reloaded := loadHistory(path)
+	if len(reloaded.entries) != maxHistory {
+		t.Fatalf("wrong entries after reload. expected=%d, got=%d", maxHistory, len(reloaded.entries))
+	}
+	for i, entry := range reloaded.entries {
+		if entry != h.entries[i] {
+			t.Fatalf("entry %d differs after reload. expected=%q, got=%q", i, h.entries[i], entry)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "lib.bpl")
+	if err := os.WriteFile(script, []byte("let double = fn(x) { x * 2 };\nlet ten = double(5);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":load " + script, ""},
+		{":env", "double: FUNCTION = fn(x) {...}\nten: INTEGER = 10\n"},
+		{":type double(ten)", "INTEGER\n"},
+		{":type [1]", "ARRAY\n"},
+		{":tokens 1 + x", "1:1    INT        \"1\"\n1:3    +          \"+\"\n1:5    IDENT      \"x\"\n1:6    EOF        \"\"\n"},
+		{":ast !x", "(!x)\nProgram [1:1-1:3]\n  Statements[0]: ExpressionStatement [1:1-1:3]\n    Expression: PrefixExpression [1:1-1:3] Operator=\"!\"\n      Right: Identifier [1:2-1:3] Value=\"x\"\n"},
+		{":reset", "environment reset\n"},
+		{":env", ""},
+		{":what", "unknown command :what, type :help for the list of commands\n"},
+	}
+
+	var out bytes.Buffer
+	s := newSession(&out, false)
+	for _, tt := range tests {
+		out.Reset()
+		if !s.runCommand(tt.input) {
+			t.Fatalf("%q ended the session", tt.input)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%q wrong output. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+	if s.runCommand(":quit") {
+		t.Errorf(":quit did not end the session")
+	}
+}
+
+func TestCheckedSession(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out, true)
+	for _, input := range []string{"", ":reset"} {
+		if input != "" {
+			s.runCommand(input)
+		}
+		out.Reset()
+		s.eval("", "9223372036854775807 + 1")
+		if !strings.Contains(out.String(), "integer overflow: 9223372036854775807 + 1") {
+			t.Errorf("after %q expected overflow error, got=%q", input, out.String())
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"BubblePL/evaluator"
+	"BubblePL/lexer"
+	"BubblePL/object"
+	"BubblePL/parser"
+	"fmt"
+	"io"
+)
+
+// runSource 解析并执行源代码，出错时把错误输出到errOut并返回exitError。
+// args 以字符串数组的形式绑定到脚本中的 args 变量，printResult 为true时输出程序最后的值，
+// checked 为true时整数运算溢出会报错
+func runSource(filename, source string, args []string, out, errOut io.Writer, printResult, checked bool) int {
+	// 第一行的 #! 会被当作 # 注释跳过
+	l := lexer.NewWithFilename(filename, source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, diagnostic := range p.Errors() {
+			io.WriteString(errOut, diagnostic.Format(source))
+		}
+		return exitError
+	}
+
+	env := object.NewEnvironment()
+	env.SetCheckedArithmetic(checked)
+	env.Set("args", newArgsArray(args))
+	evaluated := evaluator.Eval(program, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintf(errOut, "%s\n", err.StackTrace())
+		return exitError
+	}
+	if printResult && evaluated != nil && evaluated != evaluator.NULL {
+		fmt.Fprintf(out, "%s\n", evaluated.Inspect())
+	}
+	return exitOK
+}
+
+// newArgsArray 把命令行参数转换成字符串数组
+func newArgsArray(args []string) *object.Array {
+	elements := make([]object.Object, 0, len(args))
+	for _, arg := range args {
+		elements = append(elements, &object.String{Value: arg})
+	}
+	return &object.Array{Elements: elements}
+}
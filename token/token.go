@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 const (
@@ -8,6 +10,7 @@ const (
 	EOF     = "EOF"
 	/*标识符*/
 	INT   = "INT"
+	FLOAT = "FLOAT"
 	IDENT = "IDENT"
 	/*运算符*/
 	ASSIGN   = "="
@@ -16,47 +19,129 @@ const (
 	SLASH    = "/"
 	ASTERISK = "*"
 	BAND     = "!"
+	PERCENT  = "%"
+	POWER    = "POWER" // **
+	/*赋值运算符*/
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	/*逻辑运算符*/
+	AND = "AND" // &&
+	OR  = "OR"  // ||
+	/*位运算符*/
+	BIT_AND = "&"
+	BIT_OR  = "|"
+	BIT_XOR = "^"
+	BIT_NOT = "~"
+	SHL     = "SHL" // <<
+	SHR     = "SHR" // >>
 	/*比较符*/
 	LT     = "<"
 	GT     = ">"
 	EQ     = "EQ"
 	NOT_EQ = "NOT_EQ"
+	LT_EQ  = "LT_EQ"
+	GT_EQ  = "GT_EQ"
 	/*符号*/
 	COLON     = ":"
-	COMMA     = ","
-	SEMICOLON = ";"
-	LPAREN    = "("
-	RPAREN    = ")"
-	LBRACE    = "{"
-	RBRACE    = "}"
+	ELLIPSIS  = "..."
+	DOT       = "."
+	FAT_ARROW = "=>"
+	QUESTION  = "?"
+	NULLISH   = "??"
+	/*可选链*/
+	OPTIONAL_DOT      = "?."
+	OPTIONAL_LBRACKET = "?["
+	COMMA             = ","
+	SEMICOLON         = ";"
+	LPAREN            = "("
+	RPAREN            = ")"
+	LBRACE            = "{"
+	RBRACE            = "}"
 	/*关键字*/
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	IN       = "IN"
+	MATCH    = "MATCH"
 	STRING   = "STRING"
 	LBRACKET = "["
 	RBRACKET = "]"
+	/*插值字符串*/
+	INTERP_START = "INTERP_START" // "text${
+	INTERP_MID   = "INTERP_MID"   // }text${
+	INTERP_END   = "INTERP_END"   // }text"
 )
 
 // KeywordsMap 关键字的Literal到TokenType的映射
 var KeywordsMap = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"true":   TRUE,
-	"false":  FALSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"if":       IF,
+	"else":     ELSE,
+	"true":     TRUE,
+	"false":    FALSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"in":       IN,
+	"match":    MATCH,
+}
+
+// Position 源代码中的一个位置
+type Position struct {
+	Filename string // 文件名，可以为空
+	Offset   int    // 字节偏移量，从0开始
+	Line     int    // 行号，从1开始
+	Column   int    // 列号，从1开始
+}
+
+// IsValid 行号大于0的位置才是有效的位置
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String 按照 file:line:column 的格式输出位置，没有文件名时输出 line:column
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Comment 源代码中的一条注释
+type Comment struct {
+	Text string   // 注释的完整文本，包括 // # /* */ 等注释符号
+	Pos  Position // 注释第一个字符的位置
+	End  Position // 注释最后一个字符之后的位置
 }
 
 // Token 通过lexer将代码转换成一个一个的Token
 type Token struct {
 	Type    TokenType // Token的类别
 	Literal string    // Token的字面量
+	Pos     Position  // Token第一个字符的位置
+	End     Position  // Token最后一个字符之后的位置
+	Leading []Comment // Token之前的注释，只有Lexer保留注释时才会记录
 }
 
 // LookupIdentifier 根据字面量查找是否是关键字，如果是关键字就返回关键字的TokenType否则就是IDENT
@@ -68,7 +153,7 @@ func LookupIdentifier(literal string) TokenType {
 }
 
 // New 创建Token
-func New(tokenType TokenType, literal byte) Token {
+func New(tokenType TokenType, literal rune) Token {
 	return Token{
 		Type:    tokenType,
 		Literal: string(literal),